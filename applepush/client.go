@@ -6,8 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-
-	"github.com/sideshow/apns2/payload"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -15,32 +14,48 @@ import (
 	"github.com/sideshow/apns2/certificate"
 )
 
-type Client struct {
-	cfg    *config.PushServerCfg
-	client *apns2.Client
-}
-
-type aps struct {
-	Alter alter `json:"alter"`
-}
+const (
+	DEFAULT_MAX_CONNECTIONS = 1
+)
 
-type alter struct {
-	Title       string `json:"title"`
-	Subtitle    string `json:"subtitle"`
-	Body        string `json:"body"`
-	LaunchImage string `json:"launch_image"`
+type Client struct {
+	cfg     *config.PushServerCfg
+	clients []*apns2.Client //每个apns2.Client一个HTTP/2连接，请求在连接上多路复用
 }
 
+//配置了KeyId时使用.p8密钥的token认证，同一密钥可推送所有bundle id，否则使用p12证书认证
 func NewClient(cfg config.PushServerCfg) (*Client, error) {
-	cert, err := certificate.FromP12File(cfg.ExtraConfigFile, cfg.ExtraConfigFilePassword)
-	if err != nil {
-		return nil, fmt.Errorf("%s NewClient error: [%v]", cfg.Name, err)
+	if cfg.MaxConnections <= 0 {
+		cfg.MaxConnections = DEFAULT_MAX_CONNECTIONS
 	}
-	client := apns2.NewClient(cert).Production()
-	if cfg.TestMod {
-		client = apns2.NewClient(cert).Development()
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = common.DEFAULT_MAX_CONCURRENCY
 	}
-	return &Client{cfg: &cfg, client: client}, nil
+	var newClient func() *apns2.Client
+	if cfg.KeyId != "" {
+		authToken, err := getAuthToken(cfg.ExtraConfigFile, cfg.KeyId, cfg.TeamId)
+		if err != nil {
+			return nil, fmt.Errorf("%s NewClient error: [%v]", cfg.Name, err)
+		}
+		newClient = func() *apns2.Client { return apns2.NewTokenClient(authToken) }
+	} else {
+		cert, err := certificate.FromP12File(cfg.ExtraConfigFile, cfg.ExtraConfigFilePassword)
+		if err != nil {
+			return nil, fmt.Errorf("%s NewClient error: [%v]", cfg.Name, err)
+		}
+		newClient = func() *apns2.Client { return apns2.NewClient(cert) }
+	}
+	c := &Client{cfg: &cfg, clients: make([]*apns2.Client, cfg.MaxConnections)}
+	for i := range c.clients {
+		client := newClient()
+		if cfg.TestMod {
+			client = client.Development()
+		} else {
+			client = client.Production()
+		}
+		c.clients[i] = client
+	}
+	return c, nil
 }
 
 func (c *Client) Name() string {
@@ -55,53 +70,82 @@ func (c *Client) GetToken() (token string, expire_in int, err error) {
 	return "", 0, nil
 }
 
+//不同推送类型对应的topic后缀
+var topicSuffixes = map[string]string{
+	common.IOS_PUSH_TYPE_VOIP:         ".voip",
+	common.IOS_PUSH_TYPE_COMPLICATION: ".complication",
+	common.IOS_PUSH_TYPE_LIVEACTIVITY: ".push-type.liveactivity",
+}
+
 func (c *Client) formatMsg(msg *common.Msg) *apns2.Notification {
+	return BuildNotification(msg, c.cfg.Package)
+}
+
+//BuildNotification 根据通用消息生成APNs通知，topic为bundle id加推送类型后缀
+func BuildNotification(msg *common.Msg, bundleId string) *apns2.Notification {
+	option := msg.GetIos()
+	pushType := option.PushType
+	if pushType == "" {
+		pushType = common.IOS_PUSH_TYPE_ALERT
+	}
 	notification := &apns2.Notification{}
-	notification.Priority = 10 //ios push 设置最高优先级
-	notification.Topic = c.cfg.Package
-	aps := aps{}
-	aps.Alter.Title = msg.MsgTitle
-	aps.Alter.Subtitle = msg.SubMsgTile
-	if len(aps.Alter.Subtitle) > 10 {
-		aps.Alter.Subtitle = aps.Alter.Subtitle[:10]
+	notification.PushType = apns2.EPushType(pushType)
+	notification.Priority = apns2.PriorityHigh //ios push 设置最高优先级
+	if option.Priority > 0 {
+		notification.Priority = option.Priority
 	}
-	aps.Alter.Body = msg.MsgBody
-	aps.Alter.LaunchImage = msg.ImgUrl
-	payload := payload.NewPayload().Alert("hello").Badge(1).Custom("presentBadge", 1).Custom("presentSound", 1).Custom("appData", msg.MsgAction).Custom("presentAlert", 1)
-	payload = payload.AlertBody(msg.MsgBody).AlertTitle(msg.MsgTitle)
-	payload = payload.AlertAction("action").AlertActionLocKey("PLAY")
-
-	notification.Payload = payload
+	if pushType == common.IOS_PUSH_TYPE_BACKGROUND {
+		notification.Priority = apns2.PriorityLow
+	}
+	notification.Topic = bundleId + topicSuffixes[pushType]
+	notification.CollapseID = option.CollapseId
+	if option.Expiration > 0 {
+		notification.Expiration = time.Unix(option.Expiration, 0)
+	}
+	notification.Payload = BuildPayload(msg)
 	return notification
 }
 
+//按MaxConcurrency并发推送，协程轮流使用多个HTTP/2连接
 func (c *Client) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
 	notification := c.formatMsg(msg)
-	for _, token := range tokens {
-		notification.DeviceToken = token
-		res, err := c.client.Push(notification)
-		if res != nil {
-			if failsInfoMap == nil {
-				failsInfoMap = make(map[string]*common.CallbackResponseItem, 1)
-			}
-			failsInfoMap[token] = &common.CallbackResponseItem{
-				Status:       formatStatus(res),
-				Description:  res.Reason,
-				RequestId:    res.ApnsID,
-				Token:        token,
-				DeviceVendor: c.cfg.Name,
-				PackageName:  c.cfg.Package,
-			}
-		}
-		if err != nil {
-			log.WithError(err).WithField("result", res).Warnf("%s send msg Error:", c.cfg.Name)
-			return failsInfoMap, err
-		}
-		log.WithField("result", res).WithField("msg", msg).Debugf("%s send msg", c.cfg.Name)
+	failsInfoMap, err = common.PushTokens(tokens, c.cfg.MaxConcurrency, func(worker int, token string) (*common.CallbackResponseItem, error) {
+		return c.push(c.clients[worker%len(c.clients)], notification, token)
+	})
+	if err != nil {
+		return failsInfoMap, fmt.Errorf("%s push msg %v", c.cfg.Name, err)
 	}
+	log.WithField("msg", msg).Debugf("%s send msg to %d tokens", c.cfg.Name, len(tokens))
 	return failsInfoMap, nil
 }
 
+//推送单个token，复制通知避免并发修改DeviceToken
+func (c *Client) push(client *apns2.Client, notification *apns2.Notification, token string) (*common.CallbackResponseItem, error) {
+	n := *notification
+	n.DeviceToken = token
+	item := &common.CallbackResponseItem{
+		Token:        token,
+		DeviceVendor: c.cfg.Name,
+		PackageName:  c.cfg.Package,
+	}
+	res, err := client.Push(&n)
+	if err != nil {
+		log.WithError(err).WithField("token", token).Warnf("%s send msg Error:", c.cfg.Name)
+		item.Status = common.CALLBACK_STATUS_NEED_RETRY
+		item.Description = err.Error()
+		return item, err
+	}
+	item.Status = formatStatus(res)
+	item.Description = res.Reason
+	item.RequestId = res.ApnsID
+	//410 时返回token失效的时间
+	if !res.Timestamp.IsZero() {
+		item.Timestamp = res.Timestamp.Unix()
+	}
+	log.WithField("result", res).Tracef("%s send msg", c.cfg.Name)
+	return item, nil
+}
+
 func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
 	return nil, nil, nil
 }
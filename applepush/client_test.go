@@ -0,0 +1,61 @@
+package applepush
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"push_sdks/common"
+	"push_sdks/config"
+
+	"github.com/sideshow/apns2"
+)
+
+func TestPushMsgCollectsEveryToken(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "bad") {
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered","timestamp":1600000000000}`))
+			return
+		}
+		w.Header().Set("apns-id", "id-"+r.URL.Path)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := config.PushServerCfg{Name: "ios", Package: "com.example", MaxConcurrency: 4}
+	c := &Client{cfg: &cfg}
+	for i := 0; i < 2; i++ {
+		client := &apns2.Client{HTTPClient: server.Client(), Host: server.URL}
+		c.clients = append(c.clients, client)
+	}
+
+	var tokens []string
+	for i := 0; i < 20; i++ {
+		token := fmt.Sprintf("token%d", i)
+		if i%5 == 0 {
+			token += "bad"
+		}
+		tokens = append(tokens, token)
+	}
+	results, err := c.PushMsg(&common.Msg{MsgTitle: "title"}, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(tokens) {
+		t.Fatalf("expect %d results, got %d", len(tokens), len(results))
+	}
+	for _, token := range tokens {
+		status := results[token].Status
+		if strings.HasSuffix(token, "bad") {
+			if status != common.CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN {
+				t.Errorf("token %s unexpected status %d", token, status)
+			}
+		} else if status != common.CALLBACK_STATUS_OK {
+			t.Errorf("token %s unexpected status %d", token, status)
+		}
+	}
+}
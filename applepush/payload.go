@@ -0,0 +1,136 @@
+package applepush
+
+import (
+	"push_sdks/common"
+	"time"
+)
+
+const (
+	//跟客户端协商的自定义字段
+	PAYLOAD_KEY_APP_DATA  = "appData"
+	PAYLOAD_KEY_IMAGE_URL = "imageUrl"
+
+	DEFAULT_SOUND = "default"
+)
+
+type alert struct {
+	Title    string `json:"title,omitempty"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Body     string `json:"body,omitempty"`
+}
+
+//重要警告声音
+type criticalSound struct {
+	Critical int     `json:"critical"`
+	Name     string  `json:"name"`
+	Volume   float32 `json:"volume,omitempty"`
+}
+
+type aps struct {
+	Alert             *alert      `json:"alert,omitempty"`
+	Badge             *int        `json:"badge,omitempty"`
+	Sound             interface{} `json:"sound,omitempty"`
+	Category          string      `json:"category,omitempty"`
+	ThreadId          string      `json:"thread-id,omitempty"`
+	MutableContent    int         `json:"mutable-content,omitempty"`
+	InterruptionLevel string      `json:"interruption-level,omitempty"`
+	RelevanceScore    *float64    `json:"relevance-score,omitempty"`
+	TargetContentId   string      `json:"target-content-id,omitempty"`
+	ContentAvailable  int         `json:"content-available,omitempty"`
+	//实时活动
+	Timestamp      int64                  `json:"timestamp,omitempty"`
+	Event          string                 `json:"event,omitempty"`
+	ContentState   interface{}            `json:"content-state,omitempty"` //实时活动必须带content-state，空map也要输出
+	DismissalDate  int64                  `json:"dismissal-date,omitempty"`
+	StaleDate      int64                  `json:"stale-date,omitempty"`
+	AttributesType string                 `json:"attributes-type,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+}
+
+//BuildPayload 根据通用消息生成APNs payload，自定义数据与aps同级
+func BuildPayload(msg *common.Msg) map[string]interface{} {
+	option := msg.GetIos()
+	payload := make(map[string]interface{}, len(option.Custom)+3)
+	for key, val := range option.Custom {
+		payload[key] = val
+	}
+	if msg.MsgAction != "" {
+		payload[PAYLOAD_KEY_APP_DATA] = msg.MsgAction
+	}
+
+	switch option.PushType {
+	case common.IOS_PUSH_TYPE_BACKGROUND:
+		//静默推送不能带alert、badge、sound
+		payload["aps"] = &aps{ContentAvailable: 1}
+		return payload
+	case common.IOS_PUSH_TYPE_VOIP, common.IOS_PUSH_TYPE_COMPLICATION:
+		//由应用自行处理，只带自定义数据
+		payload["aps"] = &aps{}
+		return payload
+	}
+
+	data := &aps{
+		Badge:             option.Badge,
+		Category:          option.Category,
+		ThreadId:          option.ThreadId,
+		InterruptionLevel: option.InterruptionLevel,
+		RelevanceScore:    option.RelevanceScore,
+		TargetContentId:   option.TargetContentId,
+	}
+	if msg.MsgTitle != "" || msg.MsgBody != "" {
+		data.Alert = &alert{
+			Title:    msg.MsgTitle,
+			Subtitle: msg.SubMsgTile,
+			Body:     msg.MsgBody,
+		}
+	}
+	if option.PushType == common.IOS_PUSH_TYPE_LIVEACTIVITY {
+		fillLiveActivity(data, option.LiveActivity)
+		//实时活动只有带alert时才响铃
+		if data.Alert == nil {
+			payload["aps"] = data
+			return payload
+		}
+	}
+	sound := option.Sound
+	if sound == "" {
+		sound = DEFAULT_SOUND
+	}
+	data.Sound = sound
+	if option.CriticalSound {
+		data.Sound = &criticalSound{Critical: 1, Name: sound, Volume: option.SoundVolume}
+	}
+	//图片由客户端Notification Service Extension下载展示
+	if msg.ImgUrl != "" {
+		data.MutableContent = 1
+		payload[PAYLOAD_KEY_IMAGE_URL] = msg.ImgUrl
+	}
+	payload["aps"] = data
+	return payload
+}
+
+func fillLiveActivity(data *aps, activity *common.IosLiveActivity) {
+	if activity == nil {
+		activity = &common.IosLiveActivity{}
+	}
+	data.Event = activity.Event
+	if data.Event == "" {
+		data.Event = common.IOS_LIVEACTIVITY_EVENT_UPDATE
+	}
+	data.Timestamp = activity.Timestamp
+	if data.Timestamp == 0 {
+		data.Timestamp = time.Now().Unix()
+	}
+	contentState := activity.ContentState
+	if contentState == nil {
+		contentState = map[string]interface{}{}
+	}
+	data.ContentState = contentState
+	data.DismissalDate = activity.DismissalDate
+	data.StaleDate = activity.StaleDate
+	//push-to-start需要带上活动类型和静态属性
+	if data.Event == common.IOS_LIVEACTIVITY_EVENT_START {
+		data.AttributesType = activity.AttributesType
+		data.Attributes = activity.Attributes
+	}
+}
@@ -0,0 +1,97 @@
+package applepush
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+func TestBuildPayload(t *testing.T) {
+	badge := 3
+	msg := &common.Msg{
+		MsgTitle:   "title",
+		SubMsgTile: "a long subtitle that is kept",
+		MsgBody:    "body",
+		MsgAction:  "action",
+		ImgUrl:     "https://example.com/a.png",
+		Ios: &common.IosOption{
+			Badge:             &badge,
+			CriticalSound:     true,
+			SoundVolume:       0.5,
+			ThreadId:          "chat",
+			InterruptionLevel: common.IOS_INTERRUPTION_LEVEL_TIME_SENSITIVE,
+			Custom:            map[string]interface{}{"k": "v"},
+		},
+	}
+	data, err := json.Marshal(BuildPayload(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"appData":"action","aps":{"alert":{"title":"title","subtitle":"a long subtitle that is kept","body":"body"},"badge":3,"sound":{"critical":1,"name":"default","volume":0.5},"thread-id":"chat","mutable-content":1,"interruption-level":"time-sensitive"},"imageUrl":"https://example.com/a.png","k":"v"}`
+	if string(data) != expect {
+		t.Errorf("unexpected payload: %s", data)
+	}
+}
+
+func TestFormatMsgPushType(t *testing.T) {
+	c := &Client{cfg: &config.PushServerCfg{Package: "com.example"}}
+	n := c.formatMsg(&common.Msg{MsgTitle: "title", Ios: &common.IosOption{PushType: common.IOS_PUSH_TYPE_BACKGROUND, Priority: 10}})
+	data, _ := json.Marshal(n.Payload)
+	if n.Priority != 5 || n.Topic != "com.example" || string(data) != `{"aps":{"content-available":1}}` {
+		t.Errorf("unexpected background notification: %d %s %s", n.Priority, n.Topic, data)
+	}
+
+	n = c.formatMsg(&common.Msg{Ios: &common.IosOption{
+		PushType:     common.IOS_PUSH_TYPE_LIVEACTIVITY,
+		LiveActivity: &common.IosLiveActivity{Event: common.IOS_LIVEACTIVITY_EVENT_END, Timestamp: 100, DismissalDate: 200, ContentState: map[string]interface{}{"score": 1}},
+	}})
+	data, _ = json.Marshal(n.Payload)
+	if n.Topic != "com.example.push-type.liveactivity" || string(n.PushType) != "liveactivity" ||
+		string(data) != `{"aps":{"timestamp":100,"event":"end","content-state":{"score":1},"dismissal-date":200}}` {
+		t.Errorf("unexpected liveactivity notification: %s %s", n.Topic, data)
+	}
+
+	n = c.formatMsg(&common.Msg{Ios: &common.IosOption{
+		PushType: common.IOS_PUSH_TYPE_LIVEACTIVITY,
+		LiveActivity: &common.IosLiveActivity{Event: common.IOS_LIVEACTIVITY_EVENT_START, Timestamp: 100,
+			AttributesType: "MatchAttributes", Attributes: map[string]interface{}{"team": "a"}},
+	}})
+	data, _ = json.Marshal(n.Payload)
+	if string(data) != `{"aps":{"timestamp":100,"event":"start","content-state":{},"attributes-type":"MatchAttributes","attributes":{"team":"a"}}}` {
+		t.Errorf("unexpected liveactivity start notification: %s", data)
+	}
+
+	n = c.formatMsg(&common.Msg{Ios: &common.IosOption{PushType: common.IOS_PUSH_TYPE_VOIP}})
+	if n.Topic != "com.example.voip" || n.Priority != 10 {
+		t.Errorf("unexpected voip notification: %d %s", n.Priority, n.Topic)
+	}
+}
+
+func TestValidateMsg(t *testing.T) {
+	c := &Client{cfg: &config.PushServerCfg{Name: "ios"}}
+	score := 2.0
+	violations := c.ValidateMsg(&common.Msg{
+		MsgBody: strings.Repeat("a", MAX_PAYLOAD_BYTES),
+		Ios:     &common.IosOption{RelevanceScore: &score, InterruptionLevel: "loud", Priority: 7},
+	})
+	fields := map[string]bool{}
+	for _, v := range violations {
+		fields[v.Field] = true
+	}
+	if len(violations) != 4 || !fields["payload"] || !fields["relevance-score"] || !fields["interruption-level"] || !fields["apns-priority"] {
+		t.Errorf("unexpected violations %v", violations)
+	}
+	violations = c.ValidateMsg(&common.Msg{Ios: &common.IosOption{
+		PushType:     common.IOS_PUSH_TYPE_LIVEACTIVITY,
+		LiveActivity: &common.IosLiveActivity{Event: common.IOS_LIVEACTIVITY_EVENT_START},
+	}})
+	if len(violations) != 1 || violations[0].Field != "attributes-type" {
+		t.Errorf("unexpected liveactivity start violations %v", violations)
+	}
+	if violations := c.ValidateMsg(&common.Msg{MsgTitle: "title"}); len(violations) != 0 {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
@@ -0,0 +1,37 @@
+package applepush
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sideshow/apns2/token"
+)
+
+//同一个.p8密钥的所有bundle id共用一个token，JWT由token在过期前自动刷新
+var (
+	authTokens     = map[string]*token.Token{}
+	authTokensLock sync.Mutex
+)
+
+func getAuthToken(keyFile, keyId, teamId string) (*token.Token, error) {
+	if keyId == "" || teamId == "" {
+		return nil, fmt.Errorf("apns token auth need key_id and team_id")
+	}
+	key := keyFile + "_" + keyId + "_" + teamId
+	authTokensLock.Lock()
+	defer authTokensLock.Unlock()
+	if authToken, ok := authTokens[key]; ok {
+		return authToken, nil
+	}
+	authKey, err := token.AuthKeyFromFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load apns auth key:[%s] error:[%v]", keyFile, err)
+	}
+	authToken := &token.Token{
+		AuthKey: authKey,
+		KeyID:   keyId,
+		TeamID:  teamId,
+	}
+	authTokens[key] = authToken
+	return authToken, nil
+}
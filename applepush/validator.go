@@ -0,0 +1,85 @@
+package applepush
+
+import (
+	"push_sdks/common"
+
+	"github.com/sideshow/apns2"
+)
+
+const (
+	MAX_PAYLOAD_BYTES      = 4096
+	MAX_VOIP_PAYLOAD_BYTES = 5120
+)
+
+//APNs限制：payload 4KB（VoIP 5KB），打断级别、摘要分数、音量等取值范围
+var MsgRules = common.RuleSet{
+	payloadSizeRule,
+	common.EnumRule("push_type", func(msg *common.Msg) string { return msg.GetIos().PushType },
+		common.IOS_PUSH_TYPE_ALERT, common.IOS_PUSH_TYPE_BACKGROUND, common.IOS_PUSH_TYPE_VOIP,
+		common.IOS_PUSH_TYPE_LIVEACTIVITY, common.IOS_PUSH_TYPE_COMPLICATION),
+	common.EnumRule("interruption-level", func(msg *common.Msg) string { return msg.GetIos().InterruptionLevel },
+		common.IOS_INTERRUPTION_LEVEL_PASSIVE, common.IOS_INTERRUPTION_LEVEL_ACTIVE,
+		common.IOS_INTERRUPTION_LEVEL_TIME_SENSITIVE, common.IOS_INTERRUPTION_LEVEL_CRITICAL),
+	common.RangeRule("relevance-score", 0, 1, func(msg *common.Msg) (float64, bool) {
+		score := msg.GetIos().RelevanceScore
+		if score == nil {
+			return 0, false
+		}
+		return *score, true
+	}),
+	common.RangeRule("sound.volume", 0, 1, func(msg *common.Msg) (float64, bool) {
+		return float64(msg.GetIos().SoundVolume), msg.GetIos().CriticalSound
+	}),
+	common.RangeRule("badge", 0, 1<<31-1, func(msg *common.Msg) (float64, bool) {
+		badge := msg.GetIos().Badge
+		if badge == nil {
+			return 0, false
+		}
+		return float64(*badge), true
+	}),
+	priorityRule,
+	common.EnumRule("event", func(msg *common.Msg) string {
+		if activity := msg.GetIos().LiveActivity; activity != nil {
+			return activity.Event
+		}
+		return ""
+	}, common.IOS_LIVEACTIVITY_EVENT_START, common.IOS_LIVEACTIVITY_EVENT_UPDATE, common.IOS_LIVEACTIVITY_EVENT_END),
+	liveActivityStartRule,
+}
+
+//push-to-start缺少attributes-type、attributes时APNs会拒绝
+func liveActivityStartRule(msg *common.Msg) *common.Violation {
+	activity := msg.GetIos().LiveActivity
+	if activity == nil || activity.Event != common.IOS_LIVEACTIVITY_EVENT_START {
+		return nil
+	}
+	if activity.AttributesType == "" {
+		return &common.Violation{Field: "attributes-type", Rule: common.RULE_REQUIRED, Message: "must not be empty when event is start"}
+	}
+	if activity.Attributes == nil {
+		return &common.Violation{Field: "attributes", Rule: common.RULE_REQUIRED, Message: "must not be empty when event is start"}
+	}
+	return nil
+}
+
+func priorityRule(msg *common.Msg) *common.Violation {
+	switch msg.GetIos().Priority {
+	case 0, apns2.PriorityLow, apns2.PriorityHigh:
+		return nil
+	}
+	return &common.Violation{Field: "apns-priority", Rule: common.RULE_ENUM, Message: "priority must be 5 or 10"}
+}
+
+func payloadSizeRule(msg *common.Msg) *common.Violation {
+	max := MAX_PAYLOAD_BYTES
+	if msg.GetIos().PushType == common.IOS_PUSH_TYPE_VOIP {
+		max = MAX_VOIP_PAYLOAD_BYTES
+	}
+	return common.MaxBytesRule("payload", max, func(msg *common.Msg) (interface{}, error) {
+		return BuildPayload(msg), nil
+	})(msg)
+}
+
+func (c *Client) ValidateMsg(msg *common.Msg) []*common.Violation {
+	return MsgRules.Validate(c.cfg.Name, msg)
+}
@@ -0,0 +1,134 @@
+package push_sdks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"push_sdks/common"
+	"push_sdks/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//回执接收方，收到的是各厂商统一格式的回执
+type CallbackSink interface {
+	HandleCallback(deviceVendor string, response *common.CallbackResponse)
+}
+
+//支持回执校验的厂商实现，校验失败返回*common.CallbackAuthError
+//小米、vivo、OPPO开启callback_sign时校验厂商签名，各厂商都会校验回执地址中的token（华为也可用Authorization头）
+type CallbackVerifier interface {
+	VerifyCallback(r *http.Request) error
+}
+
+//各厂商校验失败被拒绝的回执数
+var callbackRejectCounts = sync.Map{}
+
+func countCallbackReject(deviceVendor string) {
+	count, _ := callbackRejectCounts.LoadOrStore(deviceVendor, new(int64))
+	atomic.AddInt64(count.(*int64), 1)
+}
+
+//返回各厂商被拒绝的回执数
+func GetCallbackRejectCounts() map[string]int64 {
+	counts := map[string]int64{}
+	callbackRejectCounts.Range(func(key, value interface{}) bool {
+		counts[key.(string)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return counts
+}
+
+func verifyCallback(r *http.Request, sdk SdkApi, cfg *config.PushServerCfg) error {
+	if err := common.CheckCallbackIp(r, cfg.Name, cfg.CallbackAllowIps); err != nil {
+		return err
+	}
+	if verifier, ok := sdk.(CallbackVerifier); ok {
+		return verifier.VerifyCallback(r)
+	}
+	return nil
+}
+
+//处理厂商回执，先校验来源ip和token，回执中的失效token会投递给InvalidTokenSink
+func ReceiveCallback(r *http.Request, name, packageName string) (*common.CallbackResponse, map[string]interface{}, error) {
+	sdk, cfg, err := findPushSdk(name, packageName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := verifyCallback(r, sdk, cfg); err != nil {
+		countCallbackReject(cfg.Name)
+		log.WithError(err).WithFields(log.Fields{
+			"deviceVendor": name,
+			"package":      packageName,
+			"remoteAddr":   r.RemoteAddr,
+		}).Warnf("reject callback")
+		return nil, nil, err
+	}
+	callbackResponse, res, err := sdk.PushReciver(r)
+	if err != nil {
+		log.WithError(err).Errorf("receive callback error client name :[%s]", name)
+		return callbackResponse, res, err
+	}
+	if callbackResponse != nil {
+		collectInvalidTokens(sdk.Name(), callbackResponse.Data)
+		notifyCallback(sdk.Name(), callbackResponse)
+	}
+	return callbackResponse, res, nil
+}
+
+//回执http处理器，回执地址由各厂商客户端通过common.CallbackUrl生成
+//路由参数：deviceVendor 厂商名，package 包名（华为等控制台配置的地址可不带）
+type CallbackHandler struct {
+	sink CallbackSink
+}
+
+func NewCallbackHandler(sink CallbackSink) *CallbackHandler {
+	return &CallbackHandler{sink: sink}
+}
+
+func (h *CallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	deviceVendor := query.Get("deviceVendor")
+	packageName := query.Get("package")
+	if deviceVendor == "" {
+		writeCallbackAck(w, http.StatusBadRequest, map[string]interface{}{"errno": 1, "errmsg": "empty deviceVendor"})
+		return
+	}
+	callbackResponse, res, err := ReceiveCallback(r, deviceVendor, packageName)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"deviceVendor": deviceVendor,
+			"package":      packageName,
+			"url":          common.RedactUri(r),
+		}).Errorf("handle callback error")
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, common.ErrCallbackUnauthorized) {
+			statusCode = http.StatusForbidden
+		}
+		writeCallbackAck(w, statusCode, map[string]interface{}{"errno": 1, "errmsg": err.Error()})
+		return
+	}
+	if h.sink != nil && callbackResponse != nil && len(callbackResponse.Data) > 0 {
+		for _, item := range callbackResponse.Data {
+			if item.DeviceVendor == "" {
+				item.DeviceVendor = deviceVendor
+			}
+		}
+		h.sink.HandleCallback(deviceVendor, callbackResponse)
+	}
+	if res == nil {
+		res = map[string]interface{}{"errno": 0, "errmsg": "success"}
+	}
+	writeCallbackAck(w, http.StatusOK, res)
+}
+
+func writeCallbackAck(w http.ResponseWriter, statusCode int, res map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.WithError(err).Errorf("write callback ack error")
+	}
+}
@@ -1,5 +1,10 @@
 package common
 
+import (
+	"net/url"
+	"strings"
+)
+
 const (
 	CALLBACK_STATUS_OK                    = 0
 	CALLBACK_STATUS_NEED_RETRY            = 1  //需要重推
@@ -13,6 +18,9 @@ const (
 	CALLBACK_STATUS_RATE_LIMIT            = 9  //vivo发送频率控制，每15分钟发一条
 	CALLBACK_STATUS_RATE_TOTAL_LIMIT      = 10 //vivo发送频率控制，每天8条
 	CALLBACK_STATUS_FORBID_TOTAL_LIMIT    = 11 //vivo发送禁止发送typeid
+	CALLBACK_STATUS_SKIPPED               = 12 //token已失效被屏蔽，未调用厂商推送
+	CALLBACK_STATUS_FILTERED              = 13 //设备不符合厂商过滤条件（网络、地区、版本等）
+	CALLBACK_STATUS_TOO_MANY_TOPICS       = 14 //设备订阅的topic数超过上限
 	UNKONW                                = 10000
 )
 
@@ -43,6 +51,12 @@ func GetCallBackMsg(status int64) string {
 		msg = "vivo发送超出单日限制"
 	case CALLBACK_STATUS_FORBID_TOTAL_LIMIT:
 		msg = "vivo禁止发送"
+	case CALLBACK_STATUS_SKIPPED:
+		msg = "token已失效，跳过推送"
+	case CALLBACK_STATUS_FILTERED:
+		msg = "设备不符合过滤条件"
+	case CALLBACK_STATUS_TOO_MANY_TOPICS:
+		msg = "订阅的topic数超过上限"
 	default:
 		msg = "UNKONW"
 
@@ -51,24 +65,69 @@ func GetCallBackMsg(status int64) string {
 
 }
 
+// 推送结果和回执的事件类型，用于统计推送漏斗
+const (
+	EVENT_TYPE_SENT      = "sent"      //厂商接收成功
+	EVENT_TYPE_DELIVERED = "delivered" //送达设备
+	EVENT_TYPE_DISPLAYED = "displayed" //通知栏展示
+	EVENT_TYPE_CLICKED   = "clicked"   //用户点击
+	EVENT_TYPE_DISMISSED = "dismissed" //用户清除
+	EVENT_TYPE_FILTERED  = "filtered"  //被过滤未下发
+	EVENT_TYPE_FAILED    = "failed"    //下发失败
+)
+
+// GetSendEventType 推送结果的事件类型
+func GetSendEventType(status int64) string {
+	switch status {
+	case CALLBACK_STATUS_OK:
+		return EVENT_TYPE_SENT
+	case CALLBACK_STATUS_SKIPPED, CALLBACK_STATUS_FILTERED:
+		return EVENT_TYPE_FILTERED
+	}
+	return EVENT_TYPE_FAILED
+}
+
 type CallbackResponse struct {
 	Data []*CallbackResponseItem `json:"data"`
 }
 
 type CallbackResponseItem struct {
-	MsgId        int64  `json:"msg_id"`
-	BiTag        string `json:"biTag"`
-	Appid        string `json:"appid"`
-	Token        string `json:"token"`
-	Status       int64  `json:"status"`
-	Timestamp    int64  `json:"timestamp"`
-	RequestId    string `json:"requestId"`
-	Description  string `json:"description"`
-	DeviceVendor string `json:"device_vendor"`
-	PackageName  string `json:"package"`
+	MsgId        int64             `json:"msg_id"`
+	BiTag        string            `json:"biTag"`
+	Appid        string            `json:"appid"`
+	Token        string            `json:"token"`
+	Status       int64             `json:"status"`
+	Timestamp    int64             `json:"timestamp"`
+	RequestId    string            `json:"requestId"`
+	Description  string            `json:"description"`
+	DeviceVendor string            `json:"device_vendor"`
+	PackageName  string            `json:"package"`
+	EventType    string            `json:"event_type"`
+	Extra        map[string]string `json:"extra,omitempty"` //发送时携带的CallbackExtra
 }
 
+// CallbackParam 回执参数，发送时使用紧凑编码，JSON标签用于解析旧版格式
 type CallbackParam struct {
-	MsgId   int64  `json:"msgId"`
-	Package string `json:"package"`
+	MsgId   int64             `json:"msgId"`
+	Package string            `json:"package,omitempty"`
+	Extra   map[string]string `json:"ext,omitempty"` //业务关联字段，如活动id、用户id、实验分组
+	Expire  int64             `json:"exp,omitempty"` //签名过期时间，秒
+	Sign    string            `json:"sig,omitempty"`
+}
+
+// CallbackUrl 回执地址，附带厂商和包名用于回执路由，token不为空时附带用于回执校验
+func CallbackUrl(redirect, deviceVendor, packageName, token string) string {
+	query := url.Values{}
+	query.Set("deviceVendor", deviceVendor)
+	if packageName != "" {
+		query.Set("package", packageName)
+	}
+	if token != "" {
+		query.Set(CALLBACK_TOKEN_PARAM, token)
+	}
+	sep := "?"
+	if strings.Contains(redirect, "?") {
+		sep = "&"
+	}
+	return redirect + sep + query.Encode()
 }
@@ -0,0 +1,155 @@
+package common
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CALLBACK_TOKEN_PARAM 回执地址中携带校验token的参数名
+const CALLBACK_TOKEN_PARAM = "cbToken"
+
+// ErrCallbackUnauthorized 回执校验失败
+var ErrCallbackUnauthorized = errors.New("callback unauthorized")
+
+// CallbackAuthError 回执校验失败的原因
+type CallbackAuthError struct {
+	DeviceVendor string
+	Reason       string
+}
+
+func (e *CallbackAuthError) Error() string {
+	return fmt.Sprintf("%s callback unauthorized: %s", e.DeviceVendor, e.Reason)
+}
+
+func (e *CallbackAuthError) Unwrap() error {
+	return ErrCallbackUnauthorized
+}
+
+// VerifyCallbackToken 校验回执地址中的token，token为空时不校验
+func VerifyCallbackToken(r *http.Request, deviceVendor, token string) error {
+	if token == "" {
+		return nil
+	}
+	if !equalToken(r.URL.Query().Get(CALLBACK_TOKEN_PARAM), token) {
+		return &CallbackAuthError{DeviceVendor: deviceVendor, Reason: "invalid callback token"}
+	}
+	return nil
+}
+
+// CALLBACK_SIGN_MAX_SKEW 回执签名时间戳与本地时间允许的最大偏差
+const CALLBACK_SIGN_MAX_SKEW = 5 * time.Minute
+
+// VerifyCallbackSign 校验厂商回执签名，expected为按厂商规则计算出的签名，比较时忽略十六进制大小写
+func VerifyCallbackSign(deviceVendor, sign, expected string) error {
+	if sign == "" {
+		return &CallbackAuthError{DeviceVendor: deviceVendor, Reason: "empty callback sign"}
+	}
+	if !equalToken(strings.ToLower(sign), strings.ToLower(expected)) {
+		return &CallbackAuthError{DeviceVendor: deviceVendor, Reason: "invalid callback sign"}
+	}
+	return nil
+}
+
+// CheckCallbackTimestamp 校验回执签名中的毫秒时间戳，防止签名被重放
+func CheckCallbackTimestamp(deviceVendor, timestamp string) error {
+	millis, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return &CallbackAuthError{DeviceVendor: deviceVendor, Reason: fmt.Sprintf("invalid callback timestamp [%s]", timestamp)}
+	}
+	skew := time.Since(time.Unix(0, millis*int64(time.Millisecond)))
+	if skew > CALLBACK_SIGN_MAX_SKEW || skew < -CALLBACK_SIGN_MAX_SKEW {
+		return &CallbackAuthError{DeviceVendor: deviceVendor, Reason: fmt.Sprintf("callback timestamp [%s] expired", timestamp)}
+	}
+	return nil
+}
+
+// ReadCallbackBody 读取回执请求体并放回，签名校验后PushReciver仍可读取
+func ReadCallbackBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// CheckCallbackIp 校验回执来源ip，allowIps支持单个ip和CIDR，为空时不校验
+// 使用RemoteAddr判断，经过反向代理时需由代理层还原真实ip
+func CheckCallbackIp(r *http.Request, deviceVendor string, allowIps []string) error {
+	if len(allowIps) == 0 {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return &CallbackAuthError{DeviceVendor: deviceVendor, Reason: fmt.Sprintf("invalid remote addr [%s]", r.RemoteAddr)}
+	}
+	for _, allow := range allowIps {
+		if strings.Contains(allow, "/") {
+			_, ipNet, err := net.ParseCIDR(allow)
+			if err == nil && ipNet.Contains(ip) {
+				return nil
+			}
+			continue
+		}
+		if allowIp := net.ParseIP(allow); allowIp != nil && allowIp.Equal(ip) {
+			return nil
+		}
+	}
+	return &CallbackAuthError{DeviceVendor: deviceVendor, Reason: fmt.Sprintf("remote ip [%s] not allowed", host)}
+}
+
+// 日志中替换敏感值的内容
+const redactedValue = "REDACTED"
+
+// RedactUri 隐藏回执地址中的校验token，用于日志
+func RedactUri(r *http.Request) string {
+	u := *r.URL
+	if query := r.URL.Query(); query.Get(CALLBACK_TOKEN_PARAM) != "" {
+		u.RawQuery = RedactForm(query).Encode()
+	}
+	return u.RequestURI()
+}
+
+// RedactHeader 隐藏回执请求头中的Authorization，用于日志
+func RedactHeader(r *http.Request) http.Header {
+	if r.Header.Get("Authorization") == "" {
+		return r.Header
+	}
+	header := r.Header.Clone()
+	header.Set("Authorization", redactedValue)
+	return header
+}
+
+// RedactForm 隐藏表单中的校验token，ParseForm后的表单包含地址参数
+func RedactForm(form url.Values) url.Values {
+	if form.Get(CALLBACK_TOKEN_PARAM) == "" {
+		return form
+	}
+	redacted := make(url.Values, len(form))
+	for k, v := range form {
+		redacted[k] = v
+	}
+	redacted.Set(CALLBACK_TOKEN_PARAM, redactedValue)
+	return redacted
+}
+
+func equalToken(actual, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) == 1
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedactCallbackRequest(t *testing.T) {
+	Convey("hide callback token and authorization in logs", t, func() {
+		r := httptest.NewRequest("POST", "/callback?deviceVendor=huawei&cbToken=secret", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		So(VerifyCallbackToken(r, "huawei", "secret"), ShouldBeNil)
+
+		So(strings.Contains(RedactUri(r), "secret"), ShouldBeFalse)
+		So(strings.Contains(RedactUri(r), "deviceVendor=huawei"), ShouldBeTrue)
+		So(RedactHeader(r).Get("Authorization"), ShouldEqual, redactedValue)
+		So(r.Header.Get("Authorization"), ShouldEqual, "Bearer secret")
+
+		So(r.ParseForm(), ShouldBeNil)
+		So(RedactForm(r.Form).Get(CALLBACK_TOKEN_PARAM), ShouldEqual, redactedValue)
+		So(r.Form.Get(CALLBACK_TOKEN_PARAM), ShouldEqual, "secret")
+	})
+}
+
+func TestVerifyCallbackSign(t *testing.T) {
+	Convey("compare callback sign ignoring hex case", t, func() {
+		So(VerifyCallbackSign("vivo", "ABCDEF", "abcdef"), ShouldBeNil)
+		So(errors.Is(VerifyCallbackSign("vivo", "", "abcdef"), ErrCallbackUnauthorized), ShouldBeTrue)
+		So(errors.Is(VerifyCallbackSign("vivo", "abcdee", "abcdef"), ErrCallbackUnauthorized), ShouldBeTrue)
+	})
+
+	Convey("reject expired or invalid timestamp", t, func() {
+		now := time.Now().UnixNano() / 1e6
+		So(CheckCallbackTimestamp("oppo", strconv.FormatInt(now, 10)), ShouldBeNil)
+		So(CheckCallbackTimestamp("oppo", strconv.FormatInt(now-int64(time.Hour/time.Millisecond), 10)), ShouldNotBeNil)
+		So(CheckCallbackTimestamp("oppo", ""), ShouldNotBeNil)
+	})
+
+	Convey("callback body can be read again after verify", t, func() {
+		r := httptest.NewRequest("POST", "/callback", strings.NewReader("data=1"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		body, err := ReadCallbackBody(r)
+		So(err, ShouldBeNil)
+		So(string(body), ShouldEqual, "data=1")
+		So(r.ParseForm(), ShouldBeNil)
+		So(r.PostForm.Get("data"), ShouldEqual, "1")
+	})
+}
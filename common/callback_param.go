@@ -0,0 +1,231 @@
+package common
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DEFAULT_CALLBACK_EXPIRE 回执参数签名默认有效期，秒
+const DEFAULT_CALLBACK_EXPIRE = 7 * 24 * 3600
+
+// 签名截取长度，厂商回执参数长度有限（一般64个字符）
+const callbackSignLength = 8
+
+// 紧凑编码版本号，base64编码后不会以{开头，用于和旧版JSON格式区分
+const callbackParamVersion = 1
+
+var errInvalidCallbackParam = errors.New("invalid callback param")
+
+func NewCallbackParam(msg *Msg, packageName string) *CallbackParam {
+	return &CallbackParam{MsgId: msg.Id, Package: packageName, Extra: msg.CallbackExtra}
+}
+
+// Encode 签名并编码回执参数，按位置写入二进制字段后base64编码，不含JSON字段名
+// 超出maxLength时先丢弃包名（回执地址中已带包名），再按key倒序丢弃业务字段，返回被丢弃的业务字段key
+// 只有msgId、有效期和签名仍超出时返回错误，调用方应不带回执参数发送
+// secret为空时不签名
+func (p *CallbackParam) Encode(secret string, expire int64, maxLength int) (string, []string, error) {
+	param := *p
+	if len(p.Extra) > 0 {
+		param.Extra = make(map[string]string, len(p.Extra))
+		for k, v := range p.Extra {
+			param.Extra[k] = v
+		}
+	}
+	if secret != "" {
+		if expire <= 0 {
+			expire = DEFAULT_CALLBACK_EXPIRE
+		}
+		param.Expire = time.Now().Unix() + expire
+	}
+	var dropped []string
+	for {
+		data := param.marshal()
+		if secret != "" {
+			data = append(data, compactSign(data, secret)...)
+		}
+		encoded := base64.RawURLEncoding.EncodeToString(data)
+		if maxLength <= 0 || len(encoded) <= maxLength {
+			return encoded, dropped, nil
+		}
+		switch {
+		case param.Package != "":
+			param.Package = ""
+		case len(param.Extra) > 0:
+			keys := param.extraKeys()
+			key := keys[len(keys)-1]
+			delete(param.Extra, key)
+			dropped = append(dropped, key)
+		default:
+			return "", dropped, fmt.Errorf("callback param length %d exceeds limit %d", len(encoded), maxLength)
+		}
+	}
+}
+
+// DecodeCallbackParam 解析回执参数，secret不为空时校验非空参数的签名和有效期
+// 兼容旧版JSON格式，用于升级前已发送消息的回执
+// 参数为空时（超长后不带参数发送、升级前未带参数）返回未经签名的空参数，回执仍可按RequestId关联
+func DecodeCallbackParam(deviceVendor, data, secret string) (*CallbackParam, error) {
+	if data == "" {
+		return &CallbackParam{}, nil
+	}
+	if strings.HasPrefix(data, "{") {
+		return decodeJsonCallbackParam(deviceVendor, data, secret)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	param, signed, err := unmarshalCallbackParam(raw)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return param, nil
+	}
+	sign := raw[signed:]
+	if len(sign) != callbackSignLength || !hmac.Equal(sign, compactSign(raw[:signed], secret)) {
+		return nil, &CallbackAuthError{DeviceVendor: deviceVendor, Reason: "invalid callback param sign"}
+	}
+	if param.Expire < time.Now().Unix() {
+		return nil, &CallbackAuthError{DeviceVendor: deviceVendor, Reason: "callback param expired"}
+	}
+	return param, nil
+}
+
+func decodeJsonCallbackParam(deviceVendor, data, secret string) (*CallbackParam, error) {
+	param := &CallbackParam{}
+	if err := json.Unmarshal([]byte(data), param); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return param, nil
+	}
+	if param.Sign == "" || !hmac.Equal([]byte(param.Sign), []byte(param.jsonSign(secret))) {
+		return nil, &CallbackAuthError{DeviceVendor: deviceVendor, Reason: "invalid callback param sign"}
+	}
+	if param.Expire < time.Now().Unix() {
+		return nil, &CallbackAuthError{DeviceVendor: deviceVendor, Reason: "callback param expired"}
+	}
+	return param, nil
+}
+
+func (p *CallbackParam) extraKeys() []string {
+	keys := make([]string, 0, len(p.Extra))
+	for k := range p.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 字段顺序：版本、msgId、exp、package、业务字段个数、按key排序的业务字段，整数为varint，字符串为长度加内容
+func (p *CallbackParam) marshal() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(callbackParamVersion)
+	writeUvarint(buf, uint64(p.MsgId))
+	writeUvarint(buf, uint64(p.Expire))
+	writeString(buf, p.Package)
+	keys := p.extraKeys()
+	writeUvarint(buf, uint64(len(keys)))
+	for _, k := range keys {
+		writeString(buf, k)
+		writeString(buf, p.Extra[k])
+	}
+	return buf.Bytes()
+}
+
+// 返回解析出的参数和签名开始的位置
+func unmarshalCallbackParam(data []byte) (*CallbackParam, int, error) {
+	reader := bytes.NewReader(data)
+	version, err := reader.ReadByte()
+	if err != nil || version != callbackParamVersion {
+		return nil, 0, errInvalidCallbackParam
+	}
+	param := &CallbackParam{}
+	msgId, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, 0, errInvalidCallbackParam
+	}
+	param.MsgId = int64(msgId)
+	expire, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, 0, errInvalidCallbackParam
+	}
+	param.Expire = int64(expire)
+	if param.Package, err = readString(reader); err != nil {
+		return nil, 0, err
+	}
+	count, err := binary.ReadUvarint(reader)
+	if err != nil || count > uint64(reader.Len()) {
+		return nil, 0, errInvalidCallbackParam
+	}
+	if count > 0 {
+		param.Extra = make(map[string]string, count)
+	}
+	for i := uint64(0); i < count; i++ {
+		k, err := readString(reader)
+		if err != nil {
+			return nil, 0, err
+		}
+		if param.Extra[k], err = readString(reader); err != nil {
+			return nil, 0, err
+		}
+	}
+	return param, len(data) - reader.Len(), nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	buf.Write(tmp[:binary.PutUvarint(tmp, v)])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func readString(reader *bytes.Reader) (string, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil || length > uint64(reader.Len()) {
+		return "", errInvalidCallbackParam
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return "", errInvalidCallbackParam
+	}
+	return string(data), nil
+}
+
+// 签名内容为编码后的全部字段
+func compactSign(data []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	return mac.Sum(nil)[:callbackSignLength]
+}
+
+// 旧版JSON格式签名内容：msgId、package、exp和按key排序的业务字段
+func (p *CallbackParam) jsonSign(secret string) string {
+	parts := []string{
+		strconv.FormatInt(p.MsgId, 10),
+		p.Package,
+		strconv.FormatInt(p.Expire, 10),
+	}
+	for _, k := range p.extraKeys() {
+		parts = append(parts, k+"="+p.Extra[k])
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignLength])
+}
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCallbackParam(t *testing.T) {
+	msg := &Msg{Id: 1234567890, CallbackExtra: map[string]string{"c": "spring", "u": "10086", "v": "b"}}
+
+	Convey("sign and verify callback param", t, func() {
+		data, dropped, err := NewCallbackParam(msg, "com.example.app").Encode("secret", 60, 200)
+		So(err, ShouldBeNil)
+		So(dropped, ShouldBeEmpty)
+		param, err := DecodeCallbackParam("xiaomi", data, "secret")
+		So(err, ShouldBeNil)
+		So(param.MsgId, ShouldEqual, msg.Id)
+		So(param.Package, ShouldEqual, "com.example.app")
+		So(param.Extra, ShouldResemble, msg.CallbackExtra)
+
+		_, err = DecodeCallbackParam("xiaomi", data, "other")
+		So(errors.Is(err, ErrCallbackUnauthorized), ShouldBeTrue)
+	})
+
+	Convey("keep extra fields within 64 characters", t, func() {
+		data, dropped, err := NewCallbackParam(msg, "com.example.app").Encode("secret", 60, 64)
+		So(err, ShouldBeNil)
+		So(dropped, ShouldBeEmpty)
+		So(len(data), ShouldBeLessThanOrEqualTo, 64)
+		param, err := DecodeCallbackParam("vivo", data, "secret")
+		So(err, ShouldBeNil)
+		So(param.MsgId, ShouldEqual, msg.Id)
+		So(param.Extra, ShouldResemble, msg.CallbackExtra)
+	})
+
+	Convey("drop extra fields and package to fit length limit", t, func() {
+		long := &Msg{Id: 9223372036854775807, CallbackExtra: map[string]string{"a": "activity-2024-spring", "b": "experiment-group-b"}}
+		data, dropped, err := NewCallbackParam(long, "com.example.app").Encode("secret", 60, 64)
+		So(err, ShouldBeNil)
+		So(dropped, ShouldResemble, []string{"b"})
+		So(len(data), ShouldBeLessThanOrEqualTo, 64)
+		param, err := DecodeCallbackParam("vivo", data, "secret")
+		So(err, ShouldBeNil)
+		So(param.MsgId, ShouldEqual, long.Id)
+		So(param.Package, ShouldEqual, "")
+		So(param.Extra, ShouldResemble, map[string]string{"a": "activity-2024-spring"})
+		So(len(long.CallbackExtra), ShouldEqual, 2)
+
+		_, _, err = NewCallbackParam(long, "").Encode("secret", 60, 16)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("unsigned param is compatible with old format", t, func() {
+		param, err := DecodeCallbackParam("oppo", `{"msgId":1,"package":"com.example.app"}`, "")
+		So(err, ShouldBeNil)
+		So(param.Package, ShouldEqual, "com.example.app")
+
+		param, err = DecodeCallbackParam("xiaomi", "", "")
+		So(err, ShouldBeNil)
+		So(param.MsgId, ShouldEqual, 0)
+	})
+
+	Convey("empty param is accepted when secret is set", t, func() {
+		param, err := DecodeCallbackParam("xiaomi", "", "secret")
+		So(err, ShouldBeNil)
+		So(param.MsgId, ShouldEqual, 0)
+		So(param.Extra, ShouldBeEmpty)
+
+		_, err = DecodeCallbackParam("xiaomi", "forged", "secret")
+		So(err, ShouldNotBeNil)
+	})
+}
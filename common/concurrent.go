@@ -0,0 +1,52 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DEFAULT_MAX_CONCURRENCY 逐个token推送的厂商默认同时发送中的请求数
+const DEFAULT_MAX_CONCURRENCY = 50
+
+// TokenPushFunc 推送单个token，worker为执行该token的协程序号
+type TokenPushFunc func(worker int, token string) (*CallbackResponseItem, error)
+
+// PushTokens 最多workers个协程并发推送，每个token单独返回结果，单个token失败不影响其他token
+// 有token失败时返回失败数和最后一个错误
+func PushTokens(tokens []string, workers int, push TokenPushFunc) (map[string]*CallbackResponseItem, error) {
+	results := make(map[string]*CallbackResponseItem, len(tokens))
+	if workers <= 0 || workers > len(tokens) {
+		workers = len(tokens)
+	}
+	var lock sync.Mutex
+	var failed int
+	var lastErr error
+	tokenChan := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for token := range tokenChan {
+				item, err := push(worker, token)
+				lock.Lock()
+				results[token] = item
+				if err != nil {
+					failed++
+					lastErr = err
+				}
+				lock.Unlock()
+			}
+		}(i)
+	}
+	for _, token := range tokens {
+		tokenChan <- token
+	}
+	close(tokenChan)
+	wg.Wait()
+
+	if lastErr != nil {
+		return results, fmt.Errorf("%d/%d failed error:[%v]", failed, len(tokens), lastErr)
+	}
+	return results, nil
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPushTokens(t *testing.T) {
+	Convey("push tokens with bounded concurrency", t, func() {
+		var running, maxRunning int32
+		tokens := []string{"a", "b", "bad", "c", "d"}
+		results, err := PushTokens(tokens, 2, func(worker int, token string) (*CallbackResponseItem, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			if token == "bad" {
+				return &CallbackResponseItem{Token: token, Status: CALLBACK_STATUS_NEED_RETRY}, errors.New("timeout")
+			}
+			return &CallbackResponseItem{Token: token, Status: CALLBACK_STATUS_OK}, nil
+		})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "1/5 failed")
+		So(len(results), ShouldEqual, len(tokens))
+		So(results["c"].Status, ShouldEqual, CALLBACK_STATUS_OK)
+		So(atomic.LoadInt32(&maxRunning), ShouldBeLessThanOrEqualTo, 2)
+	})
+}
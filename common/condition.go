@@ -0,0 +1,115 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	CONDITION_OP_TOPIC = iota //单个主题
+	CONDITION_OP_AND          //交集 &&
+	CONDITION_OP_OR           //并集 ||
+	CONDITION_OP_NOT          //取反 !
+)
+
+var topicNamePattern = regexp.MustCompile(`^[a-zA-Z0-9-_.~%]+$`)
+
+// Condition 主题条件表达式，由各厂商编译为自己的语法
+// 例：AndCondition(TopicCondition("a"), OrCondition(TopicCondition("b"), TopicCondition("c")))
+type Condition struct {
+	Op       int
+	Topic    string
+	Operands []*Condition
+}
+
+func TopicCondition(topic string) *Condition {
+	return &Condition{Op: CONDITION_OP_TOPIC, Topic: topic}
+}
+
+func AndCondition(operands ...*Condition) *Condition {
+	return &Condition{Op: CONDITION_OP_AND, Operands: operands}
+}
+
+func OrCondition(operands ...*Condition) *Condition {
+	return &Condition{Op: CONDITION_OP_OR, Operands: operands}
+}
+
+func NotCondition(operand *Condition) *Condition {
+	return &Condition{Op: CONDITION_OP_NOT, Operands: []*Condition{operand}}
+}
+
+// Validate 检查表达式结构和主题名称是否合法
+func (c *Condition) Validate() error {
+	if c == nil {
+		return errors.New("empty condition")
+	}
+	switch c.Op {
+	case CONDITION_OP_TOPIC:
+		if !topicNamePattern.MatchString(c.Topic) {
+			return fmt.Errorf("invalid topic name:[%s]", c.Topic)
+		}
+		return nil
+	case CONDITION_OP_AND, CONDITION_OP_OR:
+		if len(c.Operands) < 2 {
+			return errors.New("'and' or 'or' condition needs at least two operands")
+		}
+	case CONDITION_OP_NOT:
+		if len(c.Operands) != 1 {
+			return errors.New("'not' condition needs exactly one operand")
+		}
+	default:
+		return fmt.Errorf("invalid condition op:[%d]", c.Op)
+	}
+	for _, operand := range c.Operands {
+		if err := operand.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Topics 返回表达式中出现的主题（去重，按出现顺序）
+func (c *Condition) Topics() []string {
+	var topics []string
+	seen := map[string]bool{}
+	var walk func(cond *Condition)
+	walk = func(cond *Condition) {
+		if cond.Op == CONDITION_OP_TOPIC {
+			if !seen[cond.Topic] {
+				seen[cond.Topic] = true
+				topics = append(topics, cond.Topic)
+			}
+			return
+		}
+		for _, operand := range cond.Operands {
+			walk(operand)
+		}
+	}
+	walk(c)
+	return topics
+}
+
+// Expression 编译为 'TopicA' in topics && ('TopicB' in topics || !('TopicC' in topics)) 形式，华为和FCM通用
+func (c *Condition) Expression() string {
+	switch c.Op {
+	case CONDITION_OP_TOPIC:
+		return fmt.Sprintf("'%s' in topics", c.Topic)
+	case CONDITION_OP_NOT:
+		return "!(" + c.Operands[0].Expression() + ")"
+	}
+	sep := " && "
+	if c.Op == CONDITION_OP_OR {
+		sep = " || "
+	}
+	parts := make([]string, 0, len(c.Operands))
+	for _, operand := range c.Operands {
+		expr := operand.Expression()
+		if operand.Op == CONDITION_OP_AND || operand.Op == CONDITION_OP_OR {
+			expr = "(" + expr + ")"
+		}
+		parts = append(parts, expr)
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,22 @@
+package common
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConditionExpression(t *testing.T) {
+	Convey("compile condition expression", t, func() {
+		cond := AndCondition(TopicCondition("a"), OrCondition(TopicCondition("b"), NotCondition(TopicCondition("c"))))
+		So(cond.Validate(), ShouldBeNil)
+		So(cond.Expression(), ShouldEqual, "'a' in topics && ('b' in topics || !('c' in topics))")
+		So(cond.Topics(), ShouldResemble, []string{"a", "b", "c"})
+	})
+
+	Convey("reject invalid condition", t, func() {
+		So(AndCondition(TopicCondition("a")).Validate(), ShouldNotBeNil)
+		So(TopicCondition("a' in topics || 'b").Validate(), ShouldNotBeNil)
+		So(OrCondition(TopicCondition("a"), nil).Validate(), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,56 @@
+package common
+
+import "time"
+
+// InvalidTokenEvent 失效token事件，来源于厂商feedback接口、推送结果或回执
+type InvalidTokenEvent struct {
+	DeviceVendor string `json:"device_vendor"`
+	PackageName  string `json:"package"`
+	Token        string `json:"token"`
+	Status       int64  `json:"status"`
+	Reason       string `json:"reason"`
+	Timestamp    int64  `json:"timestamp"` //秒
+}
+
+// IsInvalidTokenStatus 判断状态是否表示token已失效，需要清理
+func IsInvalidTokenStatus(status int64) bool {
+	switch status {
+	case CALLBACK_STATUS_INVALID_DEVICE_TOKEN, CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN, CALLBACK_STATUS_UNINSTALL_APP:
+		return true
+	}
+	return false
+}
+
+// NewInvalidTokenEvent 由推送结果或回执生成失效token事件，状态不是失效状态时返回nil
+func NewInvalidTokenEvent(item *CallbackResponseItem) *InvalidTokenEvent {
+	if item == nil || item.Token == "" || !IsInvalidTokenStatus(item.Status) {
+		return nil
+	}
+	reason := item.Description
+	if reason == "" {
+		reason = GetCallBackMsg(item.Status)
+	}
+	timestamp := unixSeconds(item.Timestamp)
+	if timestamp <= 0 {
+		timestamp = time.Now().Unix()
+	}
+	return &InvalidTokenEvent{
+		DeviceVendor: item.DeviceVendor,
+		PackageName:  item.PackageName,
+		Token:        item.Token,
+		Status:       item.Status,
+		Reason:       reason,
+		Timestamp:    timestamp,
+	}
+}
+
+// 各厂商回执时间戳精度不一（秒、毫秒、微秒），统一转换为秒
+func unixSeconds(timestamp int64) int64 {
+	switch {
+	case timestamp > 1e15:
+		return timestamp / 1e6
+	case timestamp > 1e12:
+		return timestamp / 1e3
+	}
+	return timestamp
+}
@@ -1,9 +1,10 @@
 package common
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,22 +20,17 @@ func DowlodPic(url string, path, prefix, fileName string) (string, error) {
 	if fileName == "" {
 		return "", fmt.Errorf("invalid img url:[%s]", url)
 	}
-	fileName = path + "/" + prefix + fileName
+	//文件名附加url哈希，避免不同url的同名图片冲突
+	urlHash := md5.Sum([]byte(url))
+	fileName = path + "/" + prefix + hex.EncodeToString(urlHash[:8]) + "_" + fileName
 	if Exists(fileName) {
 		return fileName, nil
 	}
-	res, err := http.Get(url)
+	data, err := GetImageFetcher().Fetch(context.Background(), url)
 	if err != nil {
 		return "", err
 	}
-
-	data, err := ioutil.ReadAll(res.Body)
-	if res.StatusCode != 200 {
-		return "", fmt.Errorf("download url error res:[%v]", string(data))
-	}
-
-	err = ioutil.WriteFile(fileName, data, 0666)
-	if err != nil {
+	if err = writeFileAtomic(path, fileName, data); err != nil {
 		return "", err
 	}
 	return fileName, nil
@@ -0,0 +1,223 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrImageHostNotAllowed 图片地址不在白名单内
+var ErrImageHostNotAllowed = errors.New("image host not allowed")
+
+const imageFilePrefix = "img_"
+
+// ImageFetcher 下载推送图片，限制超时、大小、格式和来源域名
+type ImageFetcher struct {
+	Client       *http.Client
+	Timeout      time.Duration //单次下载超时
+	MaxBytes     int64         //图片最大字节数
+	AllowedHosts []string      //允许的域名，"example.com"精确匹配，".example.com"匹配子域名，为空不限制
+	Dir          string        //下载文件目录
+}
+
+// NewImageFetcher 默认超时10秒，最大5M，下载到系统临时目录下的push_sdks_img目录
+func NewImageFetcher() *ImageFetcher {
+	return &ImageFetcher{
+		Client:   &http.Client{},
+		Timeout:  10 * time.Second,
+		MaxBytes: 5 << 20,
+		Dir:      filepath.Join(os.TempDir(), "push_sdks_img"),
+	}
+}
+
+var defaultImageFetcher = NewImageFetcher()
+var defaultImageFetcherLock sync.RWMutex
+
+// SetImageFetcher 替换各厂商上传图片使用的下载器
+func SetImageFetcher(fetcher *ImageFetcher) {
+	defaultImageFetcherLock.Lock()
+	defer defaultImageFetcherLock.Unlock()
+	defaultImageFetcher = fetcher
+}
+
+func GetImageFetcher() *ImageFetcher {
+	defaultImageFetcherLock.RLock()
+	defer defaultImageFetcherLock.RUnlock()
+	return defaultImageFetcher
+}
+
+// CheckUrl 校验图片地址的协议和域名
+func (f *ImageFetcher) CheckUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return fmt.Errorf("invalid img url:[%s] %v", rawUrl, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid img url scheme:[%s]", rawUrl)
+	}
+	if len(f.AllowedHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: [%s]", ErrImageHostNotAllowed, host)
+}
+
+// Fetch 下载图片到内存，只接受PNG/JPEG
+func (f *ImageFetcher) Fetch(ctx context.Context, rawUrl string) ([]byte, error) {
+	if err := f.CheckUrl(rawUrl); err != nil {
+		return nil, err
+	}
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequest(http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download url error status:[%d] url:[%s]", res.StatusCode, rawUrl)
+	}
+	if f.MaxBytes > 0 && res.ContentLength > f.MaxBytes {
+		return nil, fmt.Errorf("%w: content length %d exceeds %d bytes", ErrInvalidImage, res.ContentLength, f.MaxBytes)
+	}
+	var reader io.Reader = res.Body
+	if f.MaxBytes > 0 {
+		reader = io.LimitReader(res.Body, f.MaxBytes+1)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if f.MaxBytes > 0 && int64(len(data)) > f.MaxBytes {
+		return nil, fmt.Errorf("%w: image exceeds %d bytes", ErrInvalidImage, f.MaxBytes)
+	}
+	if _, err = imageExt(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// FetchToFile 下载图片到Dir目录，文件名为url哈希，已下载过的直接返回
+func (f *ImageFetcher) FetchToFile(ctx context.Context, rawUrl string) (string, error) {
+	if err := f.CheckUrl(rawUrl); err != nil {
+		return "", err
+	}
+	urlHash := sha256.Sum256([]byte(rawUrl))
+	base := filepath.Join(f.Dir, imageFilePrefix+hex.EncodeToString(urlHash[:16]))
+	for _, ext := range []string{".png", ".jpg"} {
+		if Exists(base + ext) {
+			return base + ext, nil
+		}
+	}
+	data, err := f.Fetch(ctx, rawUrl)
+	if err != nil {
+		return "", err
+	}
+	ext, _ := imageExt(data)
+	fileName := base + ext
+	if err = writeFileAtomic(f.Dir, fileName, data); err != nil {
+		return "", err
+	}
+	return fileName, nil
+}
+
+// Cleanup 删除Dir目录下超过maxAge的图片文件
+func (f *ImageFetcher) Cleanup(maxAge time.Duration) error {
+	files, err := ioutil.ReadDir(f.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	deadline := time.Now().Add(-maxAge)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasPrefix(file.Name(), imageFilePrefix) {
+			continue
+		}
+		if file.ModTime().Before(deadline) {
+			os.Remove(filepath.Join(f.Dir, file.Name()))
+		}
+	}
+	return nil
+}
+
+// 跳转后的地址同样校验域名白名单
+func (f *ImageFetcher) httpClient() *http.Client {
+	client := http.Client{}
+	if f.Client != nil {
+		client = *f.Client
+	}
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := f.CheckUrl(req.URL.String()); err != nil {
+			return err
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &client
+}
+
+// 按文件头识别图片格式
+func imageExt(data []byte) (string, error) {
+	switch contentType := http.DetectContentType(data); contentType {
+	case "image/png":
+		return ".png", nil
+	case "image/jpeg":
+		return ".jpg", nil
+	default:
+		return "", fmt.Errorf("%w: unsupported content type [%s]", ErrInvalidImage, contentType)
+	}
+}
+
+// 先写临时文件再重命名，避免并发下载时读到不完整的文件，文件权限0600
+func writeFileAtomic(dir, fileName string, data []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmpFile, err := ioutil.TempFile(dir, "download_")
+	if err != nil {
+		return err
+	}
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), fileName)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+	}
+	return err
+}
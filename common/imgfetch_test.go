@@ -0,0 +1,69 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImageFetcher(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	pngData := buf.Bytes()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img.png":
+			w.Write(pngData)
+		case "/big.png":
+			w.Write(append(pngData, make([]byte, 1024)...))
+		default:
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "imgfetch")
+	defer os.RemoveAll(dir)
+	fetcher := NewImageFetcher()
+	fetcher.Dir = dir
+	fetcher.MaxBytes = int64(len(pngData)) + 100
+
+	Convey("fetch image with size cap and content sniffing", t, func() {
+		data, err := fetcher.Fetch(context.Background(), server.URL+"/img.png")
+		So(err, ShouldBeNil)
+		So(data, ShouldResemble, pngData)
+
+		_, err = fetcher.Fetch(context.Background(), server.URL+"/big.png")
+		So(errors.Is(err, ErrInvalidImage), ShouldBeTrue)
+
+		_, err = fetcher.Fetch(context.Background(), server.URL+"/page.html")
+		So(errors.Is(err, ErrInvalidImage), ShouldBeTrue)
+
+		_, err = fetcher.Fetch(context.Background(), "file:///etc/passwd")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("reject hosts outside the allowlist", t, func() {
+		limited := *fetcher
+		limited.AllowedHosts = []string{".example.com"}
+		So(limited.CheckUrl("https://img.example.com/a.png"), ShouldBeNil)
+		So(errors.Is(limited.CheckUrl(server.URL+"/img.png"), ErrImageHostNotAllowed), ShouldBeTrue)
+	})
+
+	Convey("fetch to file with 0600 permission", t, func() {
+		filePath, err := fetcher.FetchToFile(context.Background(), server.URL+"/img.png")
+		So(err, ShouldBeNil)
+		info, err := os.Stat(filePath)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+	})
+}
@@ -0,0 +1,189 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// ImageSpec 厂商图片规格，宽高为0时保持原尺寸，MaxBytes为0时不限制大小
+type ImageSpec struct {
+	Name     string
+	Width    int
+	Height   int
+	MaxBytes int
+}
+
+var (
+	OppoBigPictureSpec   = ImageSpec{Name: "oppo_big", Width: 876, Height: 324, MaxBytes: 1 << 20}
+	OppoIconSpec         = ImageSpec{Name: "oppo_icon", Width: 144, Height: 144, MaxBytes: 50 << 10}
+	XiaomiBigPictureSpec = ImageSpec{Name: "xiaomi_big", Width: 876, Height: 324, MaxBytes: 1 << 20}
+)
+
+// ErrInvalidImage 图片格式不支持或无法压缩到规格要求
+var ErrInvalidImage = errors.New("invalid image")
+
+// jpeg压缩质量，依次降低直到满足大小限制
+var jpegQualities = []int{95, 90, 85, 80, 70, 60, 50, 40}
+
+// ProcessImage 解码PNG/JPEG，居中裁剪并缩放到规格尺寸，重新编码到大小限制以内
+// 返回编码后的数据和扩展名（".png"或".jpg"）
+func ProcessImage(data []byte, spec ImageSpec) ([]byte, string, error) {
+	contentType := http.DetectContentType(data)
+	if contentType != "image/png" && contentType != "image/jpeg" {
+		return nil, "", fmt.Errorf("%w: unsupported content type [%s]", ErrInvalidImage, contentType)
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	img := resizeImage(cropImage(src, spec.Width, spec.Height), spec.Width, spec.Height)
+
+	//原图为PNG时优先保持PNG，超出大小限制再转为JPEG
+	if format == "png" {
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			return nil, "", err
+		}
+		if spec.MaxBytes <= 0 || buf.Len() <= spec.MaxBytes {
+			return buf.Bytes(), ".png", nil
+		}
+	}
+	opaque := flattenImage(img)
+	for _, quality := range jpegQualities {
+		buf := &bytes.Buffer{}
+		if err := jpeg.Encode(buf, opaque, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", err
+		}
+		if spec.MaxBytes <= 0 || buf.Len() <= spec.MaxBytes {
+			return buf.Bytes(), ".jpg", nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: can not compress image under %d bytes", ErrInvalidImage, spec.MaxBytes)
+}
+
+// ProcessImageFile 处理本地图片，处理结果写入同目录，文件名附加规格名，返回处理后的文件路径
+func ProcessImageFile(filePath string, spec ImageSpec) (string, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	out, ext, err := ProcessImage(data, spec)
+	if err != nil {
+		return "", err
+	}
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	outPath := base + "_" + spec.Name + ext
+	if err = ioutil.WriteFile(outPath, out, 0600); err != nil {
+		return "", err
+	}
+	return outPath, nil
+}
+
+// PrepareImage 下载图片并按厂商规格处理，返回处理后的本地文件路径，调用方使用后需删除
+func PrepareImage(ctx context.Context, url string, spec ImageSpec) (string, error) {
+	filePath, err := GetImageFetcher().FetchToFile(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	return ProcessImageFile(filePath, spec)
+}
+
+// 按目标宽高比居中裁剪
+func cropImage(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	if width <= 0 || height <= 0 || bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return src
+	}
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	cropW, cropH := srcW, srcW*height/width
+	if cropH > srcH {
+		cropW, cropH = srcH*width/height, srcH
+	}
+	if cropW == srcW && cropH == srcH {
+		return src
+	}
+	x0 := bounds.Min.X + (srcW-cropW)/2
+	y0 := bounds.Min.Y + (srcH-cropH)/2
+	dst := image.NewRGBA(image.Rect(0, 0, cropW, cropH))
+	draw.Draw(dst, dst.Bounds(), src, image.Pt(x0, y0), draw.Src)
+	return dst
+}
+
+// 双线性插值缩放
+func resizeImage(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	if width <= 0 || height <= 0 || (bounds.Dx() == width && bounds.Dy() == height) {
+		return src
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaleX := float64(bounds.Dx()) / float64(width)
+	scaleY := float64(bounds.Dy()) / float64(height)
+	maxX, maxY := bounds.Dx()-1, bounds.Dy()-1
+	for y := 0; y < height; y++ {
+		fy := (float64(y)+0.5)*scaleY - 0.5
+		y0, wy := splitCoord(fy, maxY)
+		y1 := minInt(y0+1, maxY)
+		for x := 0; x < width; x++ {
+			fx := (float64(x)+0.5)*scaleX - 0.5
+			x0, wx := splitCoord(fx, maxX)
+			x1 := minInt(x0+1, maxX)
+			c00 := rgba.RGBAAt(x0, y0)
+			c10 := rgba.RGBAAt(x1, y0)
+			c01 := rgba.RGBAAt(x0, y1)
+			c11 := rgba.RGBAAt(x1, y1)
+			dst.SetRGBA(x, y, color.RGBA{
+				R: bilinear(c00.R, c10.R, c01.R, c11.R, wx, wy),
+				G: bilinear(c00.G, c10.G, c01.G, c11.G, wx, wy),
+				B: bilinear(c00.B, c10.B, c01.B, c11.B, wx, wy),
+				A: bilinear(c00.A, c10.A, c01.A, c11.A, wx, wy),
+			})
+		}
+	}
+	return dst
+}
+
+// 透明背景转为白色，jpeg不支持透明度
+func flattenImage(src image.Image) image.Image {
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Over)
+	return dst
+}
+
+func splitCoord(f float64, max int) (int, float64) {
+	if f < 0 {
+		return 0, 0
+	}
+	i := int(f)
+	if i >= max {
+		return max, 0
+	}
+	return i, f - float64(i)
+}
+
+func bilinear(c00, c10, c01, c11 uint8, wx, wy float64) uint8 {
+	top := float64(c00)*(1-wx) + float64(c10)*wx
+	bottom := float64(c01)*(1-wx) + float64(c11)*wx
+	return uint8(top*(1-wy) + bottom*wy + 0.5)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testPNG(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	r := rand.New(rand.NewSource(1))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(r.Intn(256)), G: uint8(x), B: uint8(y), A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	png.Encode(buf, img)
+	return buf.Bytes()
+}
+
+func TestProcessImage(t *testing.T) {
+	Convey("crop and resize to vendor spec", t, func() {
+		data, ext, err := ProcessImage(testPNG(1000, 500), OppoBigPictureSpec)
+		So(err, ShouldBeNil)
+		So(len(data), ShouldBeLessThanOrEqualTo, OppoBigPictureSpec.MaxBytes)
+		img, _, err := image.Decode(bytes.NewReader(data))
+		So(err, ShouldBeNil)
+		So(img.Bounds().Dx(), ShouldEqual, 876)
+		So(img.Bounds().Dy(), ShouldEqual, 324)
+		So(ext, ShouldBeIn, []string{".png", ".jpg"})
+	})
+
+	Convey("re-encode as jpeg under size limit", t, func() {
+		spec := ImageSpec{Name: "test", Width: 144, Height: 144, MaxBytes: 20 << 10}
+		data, ext, err := ProcessImage(testPNG(400, 300), spec)
+		So(err, ShouldBeNil)
+		So(ext, ShouldEqual, ".jpg")
+		So(len(data), ShouldBeLessThanOrEqualTo, spec.MaxBytes)
+	})
+
+	Convey("reject unsupported format", t, func() {
+		_, _, err := ProcessImage([]byte("GIF89a not an image"), OppoIconSpec)
+		So(errors.Is(err, ErrInvalidImage), ShouldBeTrue)
+	})
+}
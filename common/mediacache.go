@@ -0,0 +1,154 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// MediaStore 厂商素材id存储，多实例部署时可替换为redis等共享存储
+type MediaStore interface {
+	Get(key string) (mediaId string, ok bool)
+	Set(key, mediaId string, ttl time.Duration)
+}
+
+// MemoryMediaStore 内存存储，过期数据在读取时删除
+type MemoryMediaStore struct {
+	lock  sync.Mutex
+	items map[string]mediaItem
+}
+
+type mediaItem struct {
+	mediaId  string
+	expireAt time.Time
+}
+
+func NewMemoryMediaStore() *MemoryMediaStore {
+	return &MemoryMediaStore{items: make(map[string]mediaItem)}
+}
+
+func (s *MemoryMediaStore) Get(key string) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	item, ok := s.items[key]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(item.expireAt) {
+		delete(s.items, key)
+		return "", false
+	}
+	return item.mediaId, true
+}
+
+func (s *MemoryMediaStore) Set(key, mediaId string, ttl time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.items[key] = mediaItem{mediaId: mediaId, expireAt: time.Now().Add(ttl)}
+}
+
+// MediaCache 缓存已上传图片的厂商素材id，同一图片的并发上传合并为一次
+type MediaCache struct {
+	store MediaStore
+	lock  sync.Mutex
+	calls map[string]*mediaCall
+}
+
+type mediaCall struct {
+	wg      sync.WaitGroup
+	mediaId string
+	err     error
+}
+
+// NewMediaCache store为空时使用内存存储
+func NewMediaCache(store MediaStore) *MediaCache {
+	if store == nil {
+		store = NewMemoryMediaStore()
+	}
+	return &MediaCache{store: store, calls: make(map[string]*mediaCall)}
+}
+
+var defaultMediaCache = NewMediaCache(nil)
+var defaultMediaCacheLock sync.RWMutex
+
+// SetMediaStore 替换默认素材缓存的存储
+func SetMediaStore(store MediaStore) {
+	defaultMediaCacheLock.Lock()
+	defer defaultMediaCacheLock.Unlock()
+	defaultMediaCache = NewMediaCache(store)
+}
+
+func GetMediaCache() *MediaCache {
+	defaultMediaCacheLock.RLock()
+	defer defaultMediaCacheLock.RUnlock()
+	return defaultMediaCache
+}
+
+// MediaCacheKey 按图片内容、厂商、应用包名和图片规格生成缓存key，各厂商统一使用包名标识应用
+func MediaCacheKey(vendor, packageName, specName string, content []byte) string {
+	sum := sha256.Sum256(content)
+	return vendor + "_" + packageName + "_" + specName + "_" + hex.EncodeToString(sum[:])
+}
+
+// GetOrUpload 命中缓存直接返回素材id，否则调用upload上传，ttl为素材在厂商的有效期
+func (c *MediaCache) GetOrUpload(key string, ttl time.Duration, upload func() (string, error)) (string, error) {
+	if mediaId, ok := c.store.Get(key); ok {
+		return mediaId, nil
+	}
+	c.lock.Lock()
+	if call, ok := c.calls[key]; ok {
+		c.lock.Unlock()
+		call.wg.Wait()
+		return call.mediaId, call.err
+	}
+	call := &mediaCall{}
+	call.wg.Add(1)
+	c.calls[key] = call
+	c.lock.Unlock()
+
+	c.upload(key, ttl, call, upload)
+	return call.mediaId, call.err
+}
+
+// upload panic时转换为错误，保证等待同一key的请求都能返回
+func (c *MediaCache) upload(key string, ttl time.Duration, call *mediaCall, upload func() (string, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.mediaId, call.err = "", fmt.Errorf("upload media panic: %v", r)
+		}
+		c.lock.Lock()
+		delete(c.calls, key)
+		c.lock.Unlock()
+		call.wg.Done()
+	}()
+	call.mediaId, call.err = upload()
+	if call.err == nil && call.mediaId != "" {
+		c.store.Set(key, call.mediaId, ttl)
+	}
+}
+
+// UploadImage 下载图片，按内容命中素材缓存，未命中时按规格处理后调用upload上传
+func UploadImage(ctx context.Context, url, vendor, packageName string, spec ImageSpec, ttl time.Duration, upload func(filePath string) (string, error)) (string, error) {
+	filePath, err := GetImageFetcher().FetchToFile(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	key := MediaCacheKey(vendor, packageName, spec.Name, content)
+	return GetMediaCache().GetOrUpload(key, ttl, func() (string, error) {
+		processedPath, err := ProcessImageFile(filePath, spec)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(processedPath)
+		return upload(processedPath)
+	})
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMediaCache(t *testing.T) {
+	Convey("collapse concurrent uploads of the same image", t, func() {
+		cache := NewMediaCache(nil)
+		key := MediaCacheKey("oppo", "app", OppoBigPictureSpec.Name, []byte("image"))
+		var uploads int32
+		upload := func() (string, error) {
+			atomic.AddInt32(&uploads, 1)
+			time.Sleep(20 * time.Millisecond)
+			return "media-id", nil
+		}
+		var wg sync.WaitGroup
+		results := make([]string, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = cache.GetOrUpload(key, time.Hour, upload)
+			}(i)
+		}
+		wg.Wait()
+		for _, result := range results {
+			So(result, ShouldEqual, "media-id")
+		}
+		mediaId, err := cache.GetOrUpload(key, time.Hour, upload)
+		So(err, ShouldBeNil)
+		So(mediaId, ShouldEqual, "media-id")
+		So(atomic.LoadInt32(&uploads), ShouldEqual, 1)
+	})
+
+	Convey("upload panic is returned as error to all waiters", t, func() {
+		cache := NewMediaCache(nil)
+		key := MediaCacheKey("xiaomi", "app", "big", []byte("panic"))
+		upload := func() (string, error) {
+			time.Sleep(20 * time.Millisecond)
+			panic("decode error")
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, 5)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = cache.GetOrUpload(key, time.Hour, upload)
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			So(err, ShouldNotBeNil)
+		}
+		mediaId, err := cache.GetOrUpload(key, time.Hour, func() (string, error) { return "media-id", nil })
+		So(err, ShouldBeNil)
+		So(mediaId, ShouldEqual, "media-id")
+	})
+
+	Convey("cache key depends on vendor and content", t, func() {
+		So(MediaCacheKey("oppo", "app", "big", []byte("a")), ShouldNotEqual, MediaCacheKey("xiaomi", "app", "big", []byte("a")))
+		So(MediaCacheKey("oppo", "app", "big", []byte("a")), ShouldNotEqual, MediaCacheKey("oppo", "app", "big", []byte("b")))
+	})
+}
@@ -14,4 +14,101 @@ type Msg struct {
 	TypeId      string
 	PackageName string
 	ChannelID   string
+	//回执中带回的业务字段，超出厂商回执参数长度限制时会被丢弃
+	CallbackExtra map[string]string
+	//iOS推送参数，为空使用默认值
+	Ios *IosOption
+	//FCM推送参数，为空使用默认值
+	Fcm *FcmOption
+	//快应用推送参数，为空打开首页
+	QuickApp *QuickAppOption
+}
+
+const (
+	IOS_INTERRUPTION_LEVEL_PASSIVE        = "passive"
+	IOS_INTERRUPTION_LEVEL_ACTIVE         = "active"
+	IOS_INTERRUPTION_LEVEL_TIME_SENSITIVE = "time-sensitive"
+	IOS_INTERRUPTION_LEVEL_CRITICAL       = "critical"
+)
+
+const (
+	IOS_PUSH_TYPE_ALERT        = "alert"
+	IOS_PUSH_TYPE_BACKGROUND   = "background"   //静默推送，唤醒应用后台更新
+	IOS_PUSH_TYPE_VOIP         = "voip"         //VoIP来电，推送到bundle id.voip
+	IOS_PUSH_TYPE_LIVEACTIVITY = "liveactivity" //实时活动更新，推送到bundle id.push-type.liveactivity
+	IOS_PUSH_TYPE_COMPLICATION = "complication" //watchOS表盘组件更新，推送到bundle id.complication
+)
+
+const (
+	IOS_LIVEACTIVITY_EVENT_START  = "start"
+	IOS_LIVEACTIVITY_EVENT_UPDATE = "update"
+	IOS_LIVEACTIVITY_EVENT_END    = "end"
+)
+
+// GetIos 未设置iOS参数时返回空参数，使用默认值
+func (m *Msg) GetIos() *IosOption {
+	if m.Ios == nil {
+		return &IosOption{}
+	}
+	return m.Ios
+}
+
+// GetFcm 未设置FCM参数时返回空参数，使用默认值
+func (m *Msg) GetFcm() *FcmOption {
+	if m.Fcm == nil {
+		return &FcmOption{}
+	}
+	return m.Fcm
+}
+
+// IosOption APNs通知参数
+type IosOption struct {
+	Badge             *int                   //角标，nil不修改，0清除
+	Sound             string                 //声音文件名，为空使用default
+	CriticalSound     bool                   //重要警告声音，需要苹果授权
+	SoundVolume       float32                //重要警告音量，0-1
+	Category          string                 //通知类别，对应客户端注册的操作按钮
+	ThreadId          string                 //通知分组
+	InterruptionLevel string                 //打断级别，见IOS_INTERRUPTION_LEVEL_*
+	RelevanceScore    *float64               //通知摘要排序分数，0-1
+	TargetContentId   string                 //点击后打开的窗口id
+	Custom            map[string]interface{} //自定义数据，与aps同级
+	PushType          string                 //推送类型，见IOS_PUSH_TYPE_*，默认alert
+	Priority          int                    //优先级，10立即发送，5省电发送，background类型固定为5
+	Expiration        int64                  //过期时间，unix秒，0表示只尝试发送一次
+	CollapseId        string                 //相同id的通知合并展示
+	LiveActivity      *IosLiveActivity       //实时活动参数，liveactivity类型必填
+}
+
+// IosLiveActivity 实时活动更新内容
+type IosLiveActivity struct {
+	Event          string                 //start、update、end
+	ContentState   map[string]interface{} //实时活动的ContentState
+	Timestamp      int64                  //内容更新时间，unix秒，为空使用当前时间
+	DismissalDate  int64                  //结束后从锁屏移除的时间，unix秒
+	StaleDate      int64                  //内容过期时间，unix秒
+	AttributesType string                 //ActivityAttributes类型名，start必填
+	Attributes     map[string]interface{} //实时活动的静态属性，start必填
+}
+
+// FcmOption FCM推送参数，其中Webpush字段同时用于华为webpush
+type FcmOption struct {
+	Data              map[string]string //数据字段
+	Priority          string            //Android投递优先级，见DeliveryPriorityHigh、DeliveryPriorityNormal
+	Ttl               int64             //离线保存时间，秒，0使用FCM默认的4周
+	CollapseKey       string            //相同key的离线消息只保留最后一条
+	Tag               string            //相同tag的通知互相替换
+	Color             string            //通知图标颜色，#rrggbb
+	Icon              string            //通知图标资源名
+	Sound             string            //声音资源名，default为系统默认声音
+	NotificationCount *int              //通知角标数
+	WebpushIcon       string            //web通知图标地址
+	WebpushLink       string            //web通知点击后打开的https链接
+	WebpushActions    []*WebPushAction  //web通知操作按钮
+}
+
+// QuickAppOption 快应用推送参数
+type QuickAppOption struct {
+	Page   string            //点击后打开的页面，默认首页
+	Params map[string]string //页面参数
 }
@@ -0,0 +1,16 @@
+package common
+
+// RecallRequest 撤回请求，Tokens为消息发送的目标token，华为撤回按token发送的消息时必填
+type RecallRequest struct {
+	RequestId string
+	Tokens    []string
+}
+
+// RecallResult 撤回结果
+type RecallResult struct {
+	DeviceVendor string `json:"device_vendor"`
+	PackageName  string `json:"package"`
+	RequestId    string `json:"requestId"`
+	Success      bool   `json:"success"`
+	Description  string `json:"description"`
+}
@@ -0,0 +1,26 @@
+package common
+
+// STATS_DATE_FORMAT 按天统计的日期格式
+const STATS_DATE_FORMAT = "20060102"
+
+// TaskStats 推送任务统计，厂商未提供的指标为0
+type TaskStats struct {
+	TaskId       string `json:"task_id"`
+	DeviceVendor string `json:"device_vendor"`
+	PackageName  string `json:"package"`
+	Sent         int64  `json:"sent"`
+	Delivered    int64  `json:"delivered"`
+	Displayed    int64  `json:"displayed"`
+	Clicked      int64  `json:"clicked"`
+}
+
+// DailyStats 应用按天推送统计，厂商未提供的指标为0
+type DailyStats struct {
+	Date         string `json:"date"` //yyyyMMdd
+	DeviceVendor string `json:"device_vendor"`
+	PackageName  string `json:"package"`
+	Sent         int64  `json:"sent"`
+	Delivered    int64  `json:"delivered"`
+	Displayed    int64  `json:"displayed"`
+	Clicked      int64  `json:"clicked"`
+}
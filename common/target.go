@@ -0,0 +1,83 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	TARGET_TYPE_TOKEN        = iota //设备token/regId
+	TARGET_TYPE_ALIAS               //别名
+	TARGET_TYPE_USER_ACCOUNT        //用户账号
+	TARGET_TYPE_TOPIC               //主题/标签
+	TARGET_TYPE_ALL                 //全部设备
+	TARGET_TYPE_CONDITION           //主题条件表达式
+)
+
+// ErrNotSupported 厂商不支持的推送能力
+var ErrNotSupported = errors.New("not supported by device vendor")
+
+// Target 推送目标，Values 为同一类型的目标列表（token、别名、账号或主题）
+type Target struct {
+	Type      int
+	Values    []string
+	Condition *Condition
+}
+
+func NewTokenTarget(tokens []string) *Target {
+	return &Target{Type: TARGET_TYPE_TOKEN, Values: tokens}
+}
+
+func NewAliasTarget(aliases ...string) *Target {
+	return &Target{Type: TARGET_TYPE_ALIAS, Values: aliases}
+}
+
+func NewUserAccountTarget(accounts ...string) *Target {
+	return &Target{Type: TARGET_TYPE_USER_ACCOUNT, Values: accounts}
+}
+
+func NewTopicTarget(topics ...string) *Target {
+	return &Target{Type: TARGET_TYPE_TOPIC, Values: topics}
+}
+
+func NewAllTarget() *Target {
+	return &Target{Type: TARGET_TYPE_ALL}
+}
+
+func NewConditionTarget(condition *Condition) *Target {
+	return &Target{Type: TARGET_TYPE_CONDITION, Condition: condition}
+}
+
+// Keys 返回推送结果map使用的key，全量推送没有目标值时使用类型名
+func (t *Target) Keys() []string {
+	if len(t.Values) > 0 {
+		return t.Values
+	}
+	return []string{GetTargetTypeName(t.Type)}
+}
+
+func GetTargetTypeName(targetType int) string {
+	name := ""
+	switch targetType {
+	case TARGET_TYPE_TOKEN:
+		name = "token"
+	case TARGET_TYPE_ALIAS:
+		name = "alias"
+	case TARGET_TYPE_USER_ACCOUNT:
+		name = "user_account"
+	case TARGET_TYPE_TOPIC:
+		name = "topic"
+	case TARGET_TYPE_ALL:
+		name = "all"
+	case TARGET_TYPE_CONDITION:
+		name = "condition"
+	default:
+		name = "UNKONW"
+	}
+	return name
+}
+
+// NotSupportedTargetError 厂商不支持该推送目标类型
+func NotSupportedTargetError(vendor string, target *Target) error {
+	return fmt.Errorf("%s target type [%s]: %w", vendor, GetTargetTypeName(target.Type), ErrNotSupported)
+}
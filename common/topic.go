@@ -0,0 +1,50 @@
+package common
+
+import (
+	"context"
+	"fmt"
+)
+
+// TopicTokenError 订阅失败的token的状态和原因
+type TopicTokenError struct {
+	Status int64
+	Reason string
+}
+
+// TopicBatchFunc 执行一批订阅或取消订阅，返回请求id和失败token在批次中的下标
+type TopicBatchFunc func(ctx context.Context, tokens []string) (requestId string, failed map[int]*TopicTokenError, err error)
+
+// ManageTopic 按batchSize分批执行，每个token单独返回结果，单批失败不影响其他批次，返回最后一个错误
+func ManageTopic(ctx context.Context, vendor, packageName, topic string, tokens []string, batchSize int, batch TopicBatchFunc) (map[string]*CallbackResponseItem, error) {
+	results := make(map[string]*CallbackResponseItem, len(tokens))
+	var lastErr error
+	for start := 0; start < len(tokens); start += batchSize {
+		end := start + batchSize
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		batchTokens := tokens[start:end]
+		requestId, failed, err := batch(ctx, batchTokens)
+		if err != nil {
+			lastErr = fmt.Errorf("%s manage topic:[%s] error:[%v]", vendor, topic, err)
+		}
+		for i, token := range batchTokens {
+			item := &CallbackResponseItem{
+				Status:       CALLBACK_STATUS_OK,
+				Token:        token,
+				DeviceVendor: vendor,
+				PackageName:  packageName,
+				RequestId:    requestId,
+			}
+			if err != nil {
+				item.Status = CALLBACK_STATUS_NEED_RETRY
+				item.Description = err.Error()
+			} else if tokenErr, ok := failed[i]; ok {
+				item.Status = tokenErr.Status
+				item.Description = tokenErr.Reason
+			}
+			results[token] = item
+		}
+	}
+	return results, lastErr
+}
@@ -0,0 +1,208 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidMsg 消息未通过厂商规则校验
+var ErrInvalidMsg = errors.New("invalid msg")
+
+// 校验规则名称
+const (
+	RULE_REQUIRED   = "required"
+	RULE_MAX_LENGTH = "max_length" //字符数上限
+	RULE_MAX_BYTES  = "max_bytes"  //序列化后字节数上限
+	RULE_RANGE      = "range"
+	RULE_ENUM       = "enum"
+	RULE_FORMAT     = "format"
+)
+
+// Violation 不符合厂商规则的字段
+type Violation struct {
+	Vendor  string `json:"vendor"`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"` //只提示不阻止发送，厂商发送时会自行降级处理
+}
+
+func (v *Violation) String() string {
+	return fmt.Sprintf("%s %s %s: %s", v.Vendor, v.Field, v.Rule, v.Message)
+}
+
+// ValidationError 包含全部校验不通过的字段，可用errors.Is(err, ErrInvalidMsg)判断
+type ValidationError struct {
+	Violations []*Violation
+}
+
+func (e *ValidationError) Error() string {
+	list := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		list = append(list, v.String())
+	}
+	return fmt.Sprintf("%v: [%s]", ErrInvalidMsg, strings.Join(list, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidMsg
+}
+
+// NewValidationError 没有不通过的字段时返回nil，Warning不算作不通过
+func NewValidationError(violations []*Violation) error {
+	var errs []*Violation
+	for _, v := range violations {
+		if !v.Warning {
+			errs = append(errs, v)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: errs}
+}
+
+// MsgRule 单条校验规则，通过时返回nil
+type MsgRule func(msg *Msg) *Violation
+
+// RuleSet 厂商的校验规则集合
+type RuleSet []MsgRule
+
+// Validate 执行全部规则，返回所有不通过的字段并填充厂商名
+func (r RuleSet) Validate(vendor string, msg *Msg) []*Violation {
+	var violations []*Violation
+	for _, rule := range r {
+		if v := rule(msg); v != nil {
+			v.Vendor = vendor
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// 通用的消息字段
+var (
+	MsgTitleField    = func(msg *Msg) string { return msg.MsgTitle }
+	MsgSubTitleField = func(msg *Msg) string { return msg.SubMsgTile }
+	MsgBodyField     = func(msg *Msg) string { return msg.MsgBody }
+	MsgActionField   = func(msg *Msg) string { return msg.MsgAction }
+	MsgImgUrlField   = func(msg *Msg) string { return msg.ImgUrl }
+)
+
+// WarningRule 将规则降级为提示，不通过时不阻止发送
+func WarningRule(rule MsgRule) MsgRule {
+	return func(msg *Msg) *Violation {
+		v := rule(msg)
+		if v != nil {
+			v.Warning = true
+		}
+		return v
+	}
+}
+
+// RequiredRule 字段不能为空
+func RequiredRule(field string, get func(msg *Msg) string) MsgRule {
+	return func(msg *Msg) *Violation {
+		if strings.TrimSpace(get(msg)) == "" {
+			return &Violation{Field: field, Rule: RULE_REQUIRED, Message: "must not be empty"}
+		}
+		return nil
+	}
+}
+
+// MaxLengthRule 字段字符数（非字节数）不能超过max
+func MaxLengthRule(field string, max int, get func(msg *Msg) string) MsgRule {
+	return func(msg *Msg) *Violation {
+		if length := utf8.RuneCountInString(get(msg)); length > max {
+			return &Violation{Field: field, Rule: RULE_MAX_LENGTH, Message: fmt.Sprintf("length %d exceeds %d", length, max)}
+		}
+		return nil
+	}
+}
+
+// MaxBytesRule build生成的内容JSON序列化后不能超过max字节，用于payload大小限制
+func MaxBytesRule(field string, max int, build func(msg *Msg) (interface{}, error)) MsgRule {
+	return func(msg *Msg) *Violation {
+		data, err := build(msg)
+		if err != nil {
+			return &Violation{Field: field, Rule: RULE_FORMAT, Message: err.Error()}
+		}
+		if data == nil {
+			return nil
+		}
+		var size int
+		if str, ok := data.(string); ok {
+			size = len(str)
+		} else {
+			body, err := json.Marshal(data)
+			if err != nil {
+				return &Violation{Field: field, Rule: RULE_FORMAT, Message: err.Error()}
+			}
+			size = len(body)
+		}
+		if size > max {
+			return &Violation{Field: field, Rule: RULE_MAX_BYTES, Message: fmt.Sprintf("size %d bytes exceeds %d", size, max)}
+		}
+		return nil
+	}
+}
+
+// EnumRule 字段不为空时必须是values之一
+func EnumRule(field string, get func(msg *Msg) string, values ...string) MsgRule {
+	return func(msg *Msg) *Violation {
+		value := get(msg)
+		if value == "" {
+			return nil
+		}
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return &Violation{Field: field, Rule: RULE_ENUM, Message: fmt.Sprintf("%q must be one of %v", value, values)}
+	}
+}
+
+// PatternRule 字段不为空时必须匹配pattern
+func PatternRule(field string, pattern *regexp.Regexp, get func(msg *Msg) string) MsgRule {
+	return func(msg *Msg) *Violation {
+		if value := get(msg); value != "" && !pattern.MatchString(value) {
+			return &Violation{Field: field, Rule: RULE_FORMAT, Message: fmt.Sprintf("%q does not match %s", value, pattern)}
+		}
+		return nil
+	}
+}
+
+// UrlRule 字段不为空时必须是指定协议的url
+func UrlRule(field string, get func(msg *Msg) string, schemes ...string) MsgRule {
+	return func(msg *Msg) *Violation {
+		value := get(msg)
+		if value == "" {
+			return nil
+		}
+		u, err := url.Parse(value)
+		if err == nil && u.Host != "" {
+			for _, scheme := range schemes {
+				if u.Scheme == scheme {
+					return nil
+				}
+			}
+		}
+		return &Violation{Field: field, Rule: RULE_FORMAT, Message: fmt.Sprintf("%q is not a valid %v url", value, schemes)}
+	}
+}
+
+// RangeRule get返回的数值存在时必须在[min, max]区间
+func RangeRule(field string, min, max float64, get func(msg *Msg) (float64, bool)) MsgRule {
+	return func(msg *Msg) *Violation {
+		if value, ok := get(msg); ok && (value < min || value > max) {
+			return &Violation{Field: field, Rule: RULE_RANGE, Message: fmt.Sprintf("%v must be in [%v, %v]", value, min, max)}
+		}
+		return nil
+	}
+}
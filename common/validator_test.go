@@ -0,0 +1,49 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRuleSetValidate(t *testing.T) {
+	rules := RuleSet{
+		RequiredRule("title", MsgTitleField),
+		MaxLengthRule("sub_title", 10, MsgSubTitleField),
+		MaxBytesRule("payload", 8, func(msg *Msg) (interface{}, error) { return msg.MsgAction, nil }),
+		UrlRule("img_url", MsgImgUrlField, "https"),
+	}
+
+	Convey("collect every violation with vendor and field", t, func() {
+		msg := &Msg{SubMsgTile: strings.Repeat("字", 11), MsgAction: "123456789", ImgUrl: "http://a.com/a.png"}
+		violations := rules.Validate("oppo", msg)
+		So(len(violations), ShouldEqual, 4)
+		So(violations[0].Vendor, ShouldEqual, "oppo")
+		So(violations[0].Rule, ShouldEqual, RULE_REQUIRED)
+		So(violations[1].Field, ShouldEqual, "sub_title")
+		So(violations[2].Rule, ShouldEqual, RULE_MAX_BYTES)
+		So(violations[3].Rule, ShouldEqual, RULE_FORMAT)
+
+		err := NewValidationError(violations)
+		So(errors.Is(err, ErrInvalidMsg), ShouldBeTrue)
+		var validationErr *ValidationError
+		So(errors.As(err, &validationErr), ShouldBeTrue)
+		So(len(validationErr.Violations), ShouldEqual, 4)
+	})
+
+	Convey("count characters instead of bytes", t, func() {
+		msg := &Msg{MsgTitle: "title", SubMsgTile: strings.Repeat("字", 10), ImgUrl: "https://a.com/a.png"}
+		So(rules.Validate("oppo", msg), ShouldBeEmpty)
+		So(NewValidationError(nil), ShouldBeNil)
+	})
+
+	Convey("warning does not block sending", t, func() {
+		warnings := RuleSet{WarningRule(RequiredRule("title", MsgTitleField))}
+		violations := warnings.Validate("oppo", &Msg{})
+		So(len(violations), ShouldEqual, 1)
+		So(violations[0].Warning, ShouldBeTrue)
+		So(NewValidationError(violations), ShouldBeNil)
+	})
+}
@@ -8,18 +8,28 @@ import (
 )
 
 type PushServerCfg struct {
-	Name                    string `yaml:"name"`
-	Redirect                string `yaml:"redirect"`
-	AppId                   string `yaml:"appid"`
-	AppSecret               string `yaml:"appsecret"`
-	AppKey                  string `yaml:"appkey"`
-	Package                 string `yaml:"package"`
-	NeedAccessToken         bool   `yaml:"need_access_token"`
-	AuthUrl                 string `yaml:"auth_url"`
-	PushUrl                 string `yaml:"push_url"` // "https://api.push.hicloud.com"
-	ExtraConfigFile         string `yaml:"extra_config_file"`
-	ExtraConfigFilePassword string `yaml:"extra_config_file_password"`
-	TestMod                 bool   `yaml:"test_mod"`
+	Name                    string   `yaml:"name"`
+	Redirect                string   `yaml:"redirect"`
+	AppId                   string   `yaml:"appid"`
+	AppSecret               string   `yaml:"appsecret"`
+	AppKey                  string   `yaml:"appkey"`
+	Package                 string   `yaml:"package"`
+	NeedAccessToken         bool     `yaml:"need_access_token"`
+	AuthUrl                 string   `yaml:"auth_url"`
+	PushUrl                 string   `yaml:"push_url"` // "https://api.push.hicloud.com"
+	ExtraConfigFile         string   `yaml:"extra_config_file"`
+	ExtraConfigFilePassword string   `yaml:"extra_config_file_password"`
+	TestMod                 bool     `yaml:"test_mod"`
+	CallbackToken           string   `yaml:"callback_token"`     //回执校验token，为空不校验
+	CallbackAllowIps        []string `yaml:"callback_allow_ips"` //回执来源ip白名单，支持CIDR，为空不限制
+	CallbackSecret          string   `yaml:"callback_secret"`    //回执参数签名密钥，为空不签名
+	CallbackSign            bool     `yaml:"callback_sign"`      //校验厂商回执签名（小米、vivo、OPPO），密钥为应用的AppSecret
+	CallbackExpire          int64    `yaml:"callback_expire"`    //回执参数签名有效期，秒，默认7天
+	KeyId                   string   `yaml:"key_id"`             //APNs .p8密钥id，配置后ExtraConfigFile为.p8文件，使用token认证
+	TeamId                  string   `yaml:"team_id"`            //APNs 开发者团队id
+	MaxConnections          int      `yaml:"max_connections"`    //APNs HTTP/2连接数，默认1
+	MaxConcurrency          int      `yaml:"max_concurrency"`    //APNs、FCM 同时发送中的请求数上限，默认50
+	TopicUrl                string   `yaml:"topic_url"`          //FCM topic订阅管理接口地址，默认https://iid.googleapis.com/iid/v1
 }
 
 func (p *PushServerCfg) GetPushServerKey() string {
@@ -39,6 +49,16 @@ func (info *PushServerCfg) GetMd5() (string, error) {
 		"ConfigFileName":     info.ExtraConfigFile,
 		"ConfigFilePassword": info.ExtraConfigFilePassword,
 		"TestMod":            info.TestMod,
+		"CallbackToken":      info.CallbackToken,
+		"CallbackAllowIps":   info.CallbackAllowIps,
+		"CallbackSecret":     info.CallbackSecret,
+		"CallbackSign":       info.CallbackSign,
+		"CallbackExpire":     info.CallbackExpire,
+		"KeyId":              info.KeyId,
+		"TeamId":             info.TeamId,
+		"MaxConnections":     info.MaxConnections,
+		"MaxConcurrency":     info.MaxConcurrency,
+		"TopicUrl":           info.TopicUrl,
 	}
 	md5Dta, err := json.Marshal(md5Map)
 	if err != nil {
@@ -0,0 +1,147 @@
+package push_sdks
+
+import (
+	"sync"
+	"time"
+
+	"push_sdks/common"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//支持拉取失效token的厂商实现（小米、OPPO feedback接口）
+type FeedbackSdkApi interface {
+	FetchInvalidTokens() ([]*common.InvalidTokenEvent, error)
+}
+
+//失效token事件的接收方，由业务方实现（如删除或标记设备token）
+type InvalidTokenSink interface {
+	HandleInvalidTokens(events []*common.InvalidTokenEvent)
+}
+
+var invalidTokenSink InvalidTokenSink
+var invalidTokenSinkLock sync.RWMutex
+
+//设置失效token接收方，推送结果和回执中的失效token也会投递给它
+func SetInvalidTokenSink(sink InvalidTokenSink) {
+	invalidTokenSinkLock.Lock()
+	defer invalidTokenSinkLock.Unlock()
+	invalidTokenSink = sink
+}
+
+func getInvalidTokenSink() InvalidTokenSink {
+	invalidTokenSinkLock.RLock()
+	defer invalidTokenSinkLock.RUnlock()
+	return invalidTokenSink
+}
+
+func deliverInvalidTokens(events []*common.InvalidTokenEvent) {
+	if len(events) == 0 {
+		return
+	}
+	suppressInvalidTokens(events)
+	sink := getInvalidTokenSink()
+	if sink == nil {
+		return
+	}
+	sink.HandleInvalidTokens(events)
+}
+
+//从推送结果或回执中提取失效token
+func collectInvalidTokens(vendor string, items []*common.CallbackResponseItem) {
+	var events []*common.InvalidTokenEvent
+	for _, item := range items {
+		event := common.NewInvalidTokenEvent(item)
+		if event == nil {
+			continue
+		}
+		if event.DeviceVendor == "" {
+			event.DeviceVendor = vendor
+		}
+		events = append(events, event)
+	}
+	deliverInvalidTokens(events)
+}
+
+func collectResultInvalidTokens(vendor string, resultList map[string]*common.CallbackResponseItem) {
+	if len(resultList) == 0 {
+		return
+	}
+	items := make([]*common.CallbackResponseItem, 0, len(resultList))
+	for _, item := range resultList {
+		items = append(items, item)
+	}
+	collectInvalidTokens(vendor, items)
+}
+
+//定期调用厂商feedback接口拉取失效token
+type FeedbackPoller struct {
+	interval time.Duration
+	sink     InvalidTokenSink
+	stop     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+//sink为空时使用SetInvalidTokenSink设置的接收方
+func NewFeedbackPoller(interval time.Duration, sink InvalidTokenSink) *FeedbackPoller {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &FeedbackPoller{
+		interval: interval,
+		sink:     sink,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (p *FeedbackPoller) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		p.PollOnce()
+		for {
+			select {
+			case <-ticker.C:
+				p.PollOnce()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (p *FeedbackPoller) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
+
+//拉取一次所有支持feedback的厂商，单个厂商失败不影响其它厂商
+func (p *FeedbackPoller) PollOnce() {
+	pushServers.Range(func(key, value interface{}) bool {
+		feedbackSdk, ok := value.(FeedbackSdkApi)
+		if !ok {
+			return true
+		}
+		events, err := feedbackSdk.FetchInvalidTokens()
+		if err != nil {
+			log.WithError(err).Errorf("fetch invalid tokens error client :[%v]", key)
+			return true
+		}
+		log.WithField("client", key).Debugf("fetch invalid tokens count:[%d]", len(events))
+		if len(events) == 0 {
+			return true
+		}
+		if p.sink != nil {
+			suppressInvalidTokens(events)
+			p.sink.HandleInvalidTokens(events)
+		} else {
+			deliverInvalidTokens(events)
+		}
+		return true
+	})
+}
@@ -0,0 +1,47 @@
+package push_sdks
+
+import (
+	"testing"
+
+	"push_sdks/common"
+)
+
+type recordSink struct {
+	events []*common.InvalidTokenEvent
+}
+
+func (s *recordSink) HandleInvalidTokens(events []*common.InvalidTokenEvent) {
+	s.events = append(s.events, events...)
+}
+
+func TestHandleSendResultsInvalidTokens(t *testing.T) {
+	sink := &recordSink{}
+	store := NewMemoryTokenStore()
+	SetInvalidTokenSink(sink)
+	SetTokenStore(store, 0)
+	defer SetInvalidTokenSink(nil)
+	defer SetTokenStore(nil, 0)
+
+	//别名推送失败，结果key是别名，不能当作失效token屏蔽
+	target := common.NewAliasTarget("alias")
+	handleSendResults("vivo", &common.Msg{}, target, map[string]*common.CallbackResponseItem{
+		"alias": {Token: "alias", Status: common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN, DeviceVendor: "vivo"},
+	}, nil)
+	if len(sink.events) != 0 {
+		t.Errorf("alias target should not deliver invalid tokens: %v", sink.events)
+	}
+	if _, ok := store.Get("vivo", "alias"); ok {
+		t.Errorf("alias target should not be suppressed")
+	}
+
+	target = common.NewTokenTarget([]string{"token"})
+	handleSendResults("vivo", &common.Msg{}, target, map[string]*common.CallbackResponseItem{
+		"token": {Token: "token", Status: common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN, DeviceVendor: "vivo"},
+	}, nil)
+	if len(sink.events) != 1 || sink.events[0].Token != "token" {
+		t.Errorf("unexpected invalid token events: %v", sink.events)
+	}
+	if _, ok := store.Get("vivo", "token"); !ok {
+		t.Errorf("invalid token should be suppressed")
+	}
+}
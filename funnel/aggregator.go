@@ -0,0 +1,149 @@
+package funnel
+
+import (
+	"push_sdks/common"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Aggregator 关联推送结果和回执，按消息、厂商统计推送漏斗
+// 通过 push_sdks.AddPushObserver 注册后自动接收推送结果和回执
+type Aggregator struct {
+	store Store
+}
+
+// NewAggregator store为空时使用内存存储
+func NewAggregator(store Store) *Aggregator {
+	if store == nil {
+		store = NewMemoryStore(0)
+	}
+	return &Aggregator{store: store}
+}
+
+// OnSendResults 记录推送结果，targets为请求推送的目标
+// results中没有的目标，推送无错误时视为厂商接收成功，否则视为失败
+func (a *Aggregator) OnSendResults(vendor string, msg *common.Msg, targets []string, results map[string]*common.CallbackResponseItem, err error) {
+	delta := &Stats{}
+	for _, target := range targets {
+		if _, ok := results[target]; ok {
+			continue
+		}
+		delta.Attempted++
+		if err != nil {
+			delta.addFailed("push error")
+		} else {
+			delta.Accepted++
+		}
+	}
+	for _, item := range results {
+		if item == nil {
+			continue
+		}
+		delta.Attempted++
+		switch common.GetSendEventType(item.Status) {
+		case common.EVENT_TYPE_SENT:
+			delta.Accepted++
+		case common.EVENT_TYPE_FILTERED:
+			delta.Filtered++
+		default:
+			delta.addFailed(common.GetCallBackMsg(item.Status))
+		}
+		if item.RequestId != "" {
+			if err := a.store.SaveRequest(vendor, item.RequestId, msg.Id); err != nil {
+				log.WithError(err).Errorf("funnel save request error msgId:[%d] requestId:[%s]", msg.Id, item.RequestId)
+			}
+		}
+	}
+	if delta.Attempted == 0 {
+		return
+	}
+	if err := a.store.Add(msg.Id, vendor, delta); err != nil {
+		log.WithError(err).Errorf("funnel add send stats error msgId:[%d]", msg.Id)
+	}
+}
+
+// OnCallback 记录回执，回执中没有消息id时按厂商请求id关联
+func (a *Aggregator) OnCallback(vendor string, response *common.CallbackResponse) {
+	if response == nil {
+		return
+	}
+	type statsKey struct {
+		msgId  int64
+		vendor string
+	}
+	deltas := map[statsKey]*Stats{}
+	for _, item := range response.Data {
+		msgId := item.MsgId
+		itemVendor := item.DeviceVendor
+		if itemVendor == "" {
+			itemVendor = vendor
+		}
+		if msgId == 0 && item.RequestId != "" {
+			id, ok, err := a.store.LookupRequest(itemVendor, item.RequestId)
+			if err != nil {
+				log.WithError(err).Errorf("funnel lookup request error requestId:[%s]", item.RequestId)
+			}
+			if ok {
+				msgId = id
+			}
+		}
+		if msgId == 0 {
+			continue
+		}
+		key := statsKey{msgId: msgId, vendor: itemVendor}
+		delta, ok := deltas[key]
+		if !ok {
+			delta = &Stats{}
+			deltas[key] = delta
+		}
+		switch item.EventType {
+		case common.EVENT_TYPE_DELIVERED:
+			delta.Delivered++
+		case common.EVENT_TYPE_DISPLAYED:
+			delta.Displayed++
+		case common.EVENT_TYPE_CLICKED:
+			delta.Clicked++
+		case common.EVENT_TYPE_DISMISSED:
+			delta.Dismissed++
+		case common.EVENT_TYPE_FILTERED:
+			delta.Filtered++
+		default:
+			delta.addFailed(common.GetCallBackMsg(item.Status))
+		}
+	}
+	for key, delta := range deltas {
+		if err := a.store.Add(key.msgId, key.vendor, delta); err != nil {
+			log.WithError(err).Errorf("funnel add callback stats error msgId:[%d]", key.msgId)
+		}
+	}
+}
+
+// MessageStats 某条消息所有厂商的汇总统计
+func (a *Aggregator) MessageStats(msgId int64) (*Stats, error) {
+	list, err := a.store.Get(msgId)
+	if err != nil {
+		return nil, err
+	}
+	total := &Stats{MsgId: msgId}
+	for _, stats := range list {
+		total.Merge(stats)
+	}
+	return total, nil
+}
+
+// MessageVendorStats 某条消息按厂商的统计
+func (a *Aggregator) MessageVendorStats(msgId int64) ([]*Stats, error) {
+	return a.store.Get(msgId)
+}
+
+// VendorStats 某个厂商所有消息的汇总统计
+func (a *Aggregator) VendorStats(vendor string) (*Stats, error) {
+	total := &Stats{DeviceVendor: vendor}
+	err := a.store.Range(func(stats *Stats) bool {
+		if stats.DeviceVendor == vendor {
+			total.Merge(stats)
+		}
+		return true
+	})
+	return total, err
+}
@@ -0,0 +1,46 @@
+package funnel
+
+import (
+	"errors"
+	"testing"
+
+	"push_sdks/common"
+)
+
+func TestAggregator(t *testing.T) {
+	a := NewAggregator(nil)
+	msg := &common.Msg{Id: 100}
+	a.OnSendResults("xiaomi", msg, []string{"t1", "t2", "t3"}, map[string]*common.CallbackResponseItem{
+		"t2": {Token: "t2", Status: common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN, RequestId: "r1"},
+		"t3": {Token: "t3", Status: common.CALLBACK_STATUS_SKIPPED},
+	}, nil)
+	a.OnSendResults("vivo", msg, []string{"t4"}, nil, errors.New("timeout"))
+	a.OnCallback("xiaomi", &common.CallbackResponse{Data: []*common.CallbackResponseItem{
+		{MsgId: 100, Token: "t1", EventType: common.EVENT_TYPE_DELIVERED},
+		{MsgId: 100, Token: "t1", EventType: common.EVENT_TYPE_CLICKED},
+		{RequestId: "r1", Token: "t2", EventType: common.EVENT_TYPE_FAILED, Status: common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN},
+		{MsgId: 0, RequestId: "unknown", EventType: common.EVENT_TYPE_DELIVERED},
+	}})
+
+	stats, err := a.MessageStats(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Attempted != 4 || stats.Accepted != 1 || stats.Filtered != 1 || stats.Failed != 3 {
+		t.Errorf("unexpected send stats: %+v", stats)
+	}
+	if stats.Delivered != 1 || stats.Clicked != 1 {
+		t.Errorf("unexpected callback stats: %+v", stats)
+	}
+	if stats.FailedReasons["push error"] != 1 {
+		t.Errorf("unexpected failed reasons: %v", stats.FailedReasons)
+	}
+
+	vendorStats, err := a.VendorStats("vivo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vendorStats.Attempted != 1 || vendorStats.Failed != 1 {
+		t.Errorf("unexpected vendor stats: %+v", vendorStats)
+	}
+}
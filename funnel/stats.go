@@ -0,0 +1,48 @@
+package funnel
+
+// Stats 推送漏斗统计
+type Stats struct {
+	MsgId         int64            `json:"msg_id"`
+	DeviceVendor  string           `json:"device_vendor"`
+	Attempted     int64            `json:"attempted"` //请求推送的目标数
+	Accepted      int64            `json:"accepted"`  //厂商接收成功
+	Delivered     int64            `json:"delivered"`
+	Displayed     int64            `json:"displayed"`
+	Clicked       int64            `json:"clicked"`
+	Dismissed     int64            `json:"dismissed"`
+	Filtered      int64            `json:"filtered"`       //被过滤或屏蔽未下发
+	Failed        int64            `json:"failed"`         //推送或下发失败
+	FailedReasons map[string]int64 `json:"failed_reasons"` //按失败原因统计
+}
+
+// Merge 累加另一份统计
+func (s *Stats) Merge(delta *Stats) {
+	s.Attempted += delta.Attempted
+	s.Accepted += delta.Accepted
+	s.Delivered += delta.Delivered
+	s.Displayed += delta.Displayed
+	s.Clicked += delta.Clicked
+	s.Dismissed += delta.Dismissed
+	s.Filtered += delta.Filtered
+	s.Failed += delta.Failed
+	for reason, count := range delta.FailedReasons {
+		if s.FailedReasons == nil {
+			s.FailedReasons = make(map[string]int64)
+		}
+		s.FailedReasons[reason] += count
+	}
+}
+
+func (s *Stats) addFailed(reason string) {
+	s.Failed++
+	if s.FailedReasons == nil {
+		s.FailedReasons = make(map[string]int64)
+	}
+	s.FailedReasons[reason]++
+}
+
+func (s *Stats) clone() *Stats {
+	c := &Stats{MsgId: s.MsgId, DeviceVendor: s.DeviceVendor}
+	c.Merge(s)
+	return c
+}
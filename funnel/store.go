@@ -0,0 +1,128 @@
+package funnel
+
+import (
+	"sync"
+	"time"
+)
+
+// Store 漏斗统计存储，可替换为redis、数据库等实现
+type Store interface {
+	// Add 累加某条消息某个厂商的统计
+	Add(msgId int64, vendor string, delta *Stats) error
+	// Get 返回某条消息各厂商的统计
+	Get(msgId int64) ([]*Stats, error)
+	// Range 遍历所有统计，fn返回false时停止
+	Range(fn func(stats *Stats) bool) error
+	// SaveRequest 保存厂商请求id与消息id的对应关系，用于关联不带消息id的回执
+	SaveRequest(vendor, requestId string, msgId int64) error
+	// LookupRequest 按厂商请求id查找消息id
+	LookupRequest(vendor, requestId string) (int64, bool, error)
+}
+
+// MemoryStore 内存存储，超过ttl未更新的消息统计被清理
+type MemoryStore struct {
+	lock      sync.Mutex
+	ttl       time.Duration
+	stats     map[int64]map[string]*Stats
+	updatedAt map[int64]time.Time
+	requests  map[string]int64
+	lastClean time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	return &MemoryStore{
+		ttl:       ttl,
+		stats:     make(map[int64]map[string]*Stats),
+		updatedAt: make(map[int64]time.Time),
+		requests:  make(map[string]int64),
+		lastClean: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Add(msgId int64, vendor string, delta *Stats) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	s.clean(now)
+	vendors, ok := s.stats[msgId]
+	if !ok {
+		vendors = make(map[string]*Stats)
+		s.stats[msgId] = vendors
+	}
+	stats, ok := vendors[vendor]
+	if !ok {
+		stats = &Stats{MsgId: msgId, DeviceVendor: vendor}
+		vendors[vendor] = stats
+	}
+	stats.Merge(delta)
+	s.updatedAt[msgId] = now
+	return nil
+}
+
+func (s *MemoryStore) Get(msgId int64) ([]*Stats, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := make([]*Stats, 0, len(s.stats[msgId]))
+	for _, stats := range s.stats[msgId] {
+		list = append(list, stats.clone())
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) Range(fn func(stats *Stats) bool) error {
+	s.lock.Lock()
+	list := make([]*Stats, 0, len(s.stats))
+	for _, vendors := range s.stats {
+		for _, stats := range vendors {
+			list = append(list, stats.clone())
+		}
+	}
+	s.lock.Unlock()
+	for _, stats := range list {
+		if !fn(stats) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SaveRequest(vendor, requestId string, msgId int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests[vendor+"_"+requestId] = msgId
+	return nil
+}
+
+func (s *MemoryStore) LookupRequest(vendor, requestId string) (int64, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	msgId, ok := s.requests[vendor+"_"+requestId]
+	return msgId, ok, nil
+}
+
+//每隔一个ttl清理一次过期消息
+func (s *MemoryStore) clean(now time.Time) {
+	if now.Sub(s.lastClean) < s.ttl {
+		return
+	}
+	s.lastClean = now
+	expired := map[int64]bool{}
+	for msgId, updatedAt := range s.updatedAt {
+		if now.Sub(updatedAt) > s.ttl {
+			expired[msgId] = true
+			delete(s.stats, msgId)
+			delete(s.updatedAt, msgId)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	for key, msgId := range s.requests {
+		if expired[msgId] {
+			delete(s.requests, key)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package googlepush
 import (
 	"push_sdks/config"
 	"push_sdks/common"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/messaging"
@@ -13,6 +15,9 @@ import (
 	"google.golang.org/api/option"
 )
 
+//全量推送使用的topic，客户端启动时需订阅该topic
+var BroadcastTopic = "all"
+
 //Client
 type Client struct {
 	cfg       *config.PushServerCfg
@@ -49,23 +54,33 @@ func (c *Client) GetToken() (token string, expire_in int, err error) {
 	return token, 0, err
 }
 
+//与fcm客户端使用相同的Android、APNs、Webpush配置，转换为firebase sdk的消息
+func (c *Client) formatMsg(msg *common.Msg) (*messaging.Message, error) {
+	data, err := json.Marshal(formatFcmMessage(msg))
+	if err != nil {
+		return nil, err
+	}
+	message := &messaging.Message{}
+	if err = json.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	//firebase sdk只接受小写的优先级
+	message.Android.Priority = strings.ToLower(message.Android.Priority)
+	return message, nil
+}
+
 func (c *Client) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
-	message := &messaging.MulticastMessage{
-		Data: map[string]string{
-			"score": "850",
-		},
-		Tokens: tokens,
+	formatted, err := c.formatMsg(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%s format msg error:[%v]", c.cfg.Name, err)
 	}
-	message.Notification = &messaging.Notification{}
-	message.Notification.Title = msg.MsgTitle
-	message.Notification.Body = msg.MsgBody
-	message.Notification.ImageURL = msg.ImgUrl
-	message.Android = &messaging.AndroidConfig{}
-	message.Android.Notification = &messaging.AndroidNotification{
-		Title:       msg.MsgTitle,
-		Body:        msg.MsgBody,
-		ClickAction: "first_open",
-		Visibility:  messaging.VisibilityPrivate,
+	message := &messaging.MulticastMessage{
+		Tokens:       tokens,
+		Data:         formatted.Data,
+		Notification: formatted.Notification,
+		Android:      formatted.Android,
+		Webpush:      formatted.Webpush,
+		APNS:         formatted.APNS,
 	}
 
 	br, err := c.msgClient.SendMulticast(context.Background(), message)
@@ -76,6 +91,61 @@ func (c *Client) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[str
 	return nil, err
 }
 
+//按目标类型推送，支持token、单个topic和条件表达式
+func (c *Client) PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	switch target.Type {
+	case common.TARGET_TYPE_TOKEN:
+		return c.PushMsg(msg, target.Values)
+	case common.TARGET_TYPE_TOPIC:
+		if len(target.Values) != 1 {
+			return nil, fmt.Errorf("%s only one topic can be pushed at a time", c.cfg.Name)
+		}
+	case common.TARGET_TYPE_CONDITION:
+	default:
+		return nil, common.NotSupportedTargetError(c.cfg.Name, target)
+	}
+	topic := target.Keys()[0]
+	message, err := c.formatMsg(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%s format msg error:[%v]", c.cfg.Name, err)
+	}
+	message.Topic = topic
+	if target.Type == common.TARGET_TYPE_CONDITION {
+		message.Topic = ""
+		message.Condition, err = FormatCondition(target.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid condition:[%v]", c.cfg.Name, err)
+		}
+	}
+	msgId, err := c.msgClient.Send(context.Background(), message)
+	failsInfoMap = map[string]*common.CallbackResponseItem{
+		topic: {
+			Status:       common.CALLBACK_STATUS_OK,
+			MsgId:        msg.Id,
+			RequestId:    msgId,
+			Token:        topic,
+			DeviceVendor: c.cfg.Name,
+			PackageName:  c.cfg.Package,
+		},
+	}
+	if err != nil {
+		failsInfoMap[topic].Status = common.CALLBACK_STATUS_NEED_RETRY
+		failsInfoMap[topic].Description = err.Error()
+		return failsInfoMap, fmt.Errorf("%s push topic msg error:[%v] message:[%v]", c.cfg.Name, err, message)
+	}
+	log.Infof("%s push topic msg result:[%v] message:[%v]", c.cfg.Name, msgId, message)
+	return failsInfoMap, nil
+}
+
+//全量推送，通过所有设备订阅的BroadcastTopic实现，返回消息id
+func (c *Client) BroadcastAll(msg *common.Msg) (string, error) {
+	resultMap, err := c.PushTargetMsg(msg, common.NewTopicTarget(BroadcastTopic))
+	if err != nil {
+		return "", err
+	}
+	return resultMap[BroadcastTopic].RequestId, nil
+}
+
 func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
 	return nil, nil, nil
 }
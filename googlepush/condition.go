@@ -0,0 +1,21 @@
+package googlepush
+
+import (
+	"fmt"
+
+	"push_sdks/common"
+)
+
+//FCM 条件表达式最多包含5个主题
+const MaxConditionTopics = 5
+
+//FormatCondition 编译为FCM条件表达式 'TopicA' in topics && ('TopicB' in topics || 'TopicC' in topics)
+func FormatCondition(condition *common.Condition) (string, error) {
+	if err := condition.Validate(); err != nil {
+		return "", err
+	}
+	if topics := condition.Topics(); len(topics) > MaxConditionTopics {
+		return "", fmt.Errorf("fcm condition supports at most %d topics, got %d", MaxConditionTopics, len(topics))
+	}
+	return condition.Expression(), nil
+}
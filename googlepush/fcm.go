@@ -0,0 +1,298 @@
+package googlepush
+
+import (
+	"push_sdks/applepush"
+	"push_sdks/clients"
+	"push_sdks/common"
+	"push_sdks/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DEFAULT_PUSH_URL = "https://fcm.googleapis.com"
+	SEND_MESSAGE_FMT = "%s/v1/projects/%s/messages:send" //参数为PushUrl和项目id
+)
+
+//FcmClient 直接调用FCM HTTP v1接口，不依赖firebase sdk
+//ExtraConfigFile为服务账号json，AppId可覆盖项目id，AuthUrl、PushUrl、TopicUrl可覆盖默认地址
+type FcmClient struct {
+	cfg     *config.PushServerCfg
+	client  *clients.HTTPClient
+	tokens   *tokenSource
+	sendUrl  string
+	topicUrl string
+}
+
+func NewFcmClient(cfg config.PushServerCfg) (*FcmClient, error) {
+	account, err := LoadServiceAccount(cfg.ExtraConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s load service account error:[%v]", cfg.Name, err)
+	}
+	projectId := cfg.AppId
+	if projectId == "" {
+		projectId = account.ProjectId
+	}
+	if projectId == "" {
+		return nil, fmt.Errorf("%s empty project id", cfg.Name)
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = common.DEFAULT_MAX_CONCURRENCY
+	}
+	pushUrl := strings.TrimRight(cfg.PushUrl, "/")
+	if pushUrl == "" {
+		pushUrl = DEFAULT_PUSH_URL
+	}
+	topicUrl := strings.TrimRight(cfg.TopicUrl, "/")
+	if topicUrl == "" {
+		topicUrl = DEFAULT_TOPIC_URL
+	}
+	client := clients.NewHTTPClient()
+	tokens, err := newTokenSource(account, cfg.AuthUrl, client)
+	if err != nil {
+		return nil, fmt.Errorf("%s init token source error:[%v]", cfg.Name, err)
+	}
+	return &FcmClient{
+		cfg:      &cfg,
+		client:   client,
+		tokens:   tokens,
+		sendUrl:  fmt.Sprintf(SEND_MESSAGE_FMT, pushUrl, projectId),
+		topicUrl: topicUrl,
+	}, nil
+}
+
+func (c *FcmClient) NeedAccessToken() bool {
+	return c.cfg.NeedAccessToken
+}
+
+func (c *FcmClient) Name() string {
+	return c.cfg.Name
+}
+
+func (c *FcmClient) GetToken() (token string, expire_in int, err error) {
+	return c.tokens.Token(context.Background())
+}
+
+//生成Android、APNs、Webpush三个平台的消息，APNs部分与applepush使用相同的payload
+//数据字段放在消息级别，对iOS和web同样生效
+func formatFcmMessage(msg *common.Msg) *FcmMessage {
+	option := msg.GetFcm()
+	data := make(map[string]string, len(option.Data)+1)
+	for key, val := range option.Data {
+		data[key] = val
+	}
+	if msg.MsgAction != "" {
+		data[applepush.PAYLOAD_KEY_APP_DATA] = msg.MsgAction
+	}
+	if len(data) == 0 {
+		data = nil
+	}
+
+	android := &FcmAndroidConfig{
+		CollapseKey: option.CollapseKey,
+		Priority:    option.Priority,
+		Notification: &FcmAndroidNotification{
+			Title:             msg.MsgTitle,
+			Body:              msg.MsgBody,
+			Icon:              option.Icon,
+			Color:             option.Color,
+			Sound:             option.Sound,
+			Tag:               option.Tag,
+			ClickAction:       "first_open",
+			ChannelId:         msg.ChannelID,
+			Image:             msg.ImgUrl,
+			Visibility:        common.VisibilityPrivate,
+			NotificationCount: option.NotificationCount,
+		},
+	}
+	if option.Ttl > 0 {
+		android.Ttl = strconv.FormatInt(option.Ttl, 10) + "s"
+	}
+
+	notification := applepush.BuildNotification(msg, "")
+	apns := &FcmApnsConfig{
+		Headers: map[string]string{
+			"apns-push-type": string(notification.PushType),
+			"apns-priority":  strconv.Itoa(notification.Priority),
+		},
+		Payload: applepush.BuildPayload(msg),
+	}
+	if !notification.Expiration.IsZero() {
+		apns.Headers["apns-expiration"] = strconv.FormatInt(notification.Expiration.Unix(), 10)
+	}
+	if notification.CollapseID != "" {
+		apns.Headers["apns-collapse-id"] = notification.CollapseID
+	}
+
+	webpush := &FcmWebpushConfig{
+		Notification: &FcmWebpushNotification{
+			Title:   msg.MsgTitle,
+			Body:    msg.MsgBody,
+			Icon:    option.WebpushIcon,
+			Image:   msg.ImgUrl,
+			Tag:     option.Tag,
+			Actions: option.WebpushActions,
+		},
+	}
+	if option.Ttl > 0 {
+		webpush.Headers = map[string]string{"TTL": strconv.FormatInt(option.Ttl, 10)}
+	}
+	if option.WebpushLink != "" {
+		webpush.FcmOptions = &FcmWebpushOptions{Link: option.WebpushLink}
+	}
+
+	return &FcmMessage{
+		Data: data,
+		Notification: &FcmNotification{
+			Title: msg.MsgTitle,
+			Body:  msg.MsgBody,
+			Image: msg.ImgUrl,
+		},
+		Android: android,
+		Apns:    apns,
+		Webpush: webpush,
+	}
+}
+
+//FCM v1每次只能发送一个token，多个token按MaxConcurrency并发发送
+func (c *FcmClient) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	message := formatFcmMessage(msg)
+	failsInfoMap, err = common.PushTokens(tokens, c.cfg.MaxConcurrency, func(worker int, token string) (*common.CallbackResponseItem, error) {
+		tokenMessage := *message
+		tokenMessage.Token = token
+		return c.send(context.Background(), msg, &tokenMessage, token)
+	})
+	if err != nil {
+		return failsInfoMap, fmt.Errorf("%s push msg %v", c.cfg.Name, err)
+	}
+	log.WithField("msg", msg).Debugf("%s send msg to %d tokens", c.cfg.Name, len(tokens))
+	return failsInfoMap, nil
+}
+
+//按目标类型推送，支持token、单个topic和条件表达式
+func (c *FcmClient) PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	message := formatFcmMessage(msg)
+	switch target.Type {
+	case common.TARGET_TYPE_TOKEN:
+		return c.PushMsg(msg, target.Values)
+	case common.TARGET_TYPE_TOPIC:
+		if len(target.Values) != 1 {
+			return nil, fmt.Errorf("%s only one topic can be pushed at a time", c.cfg.Name)
+		}
+		message.Topic = target.Values[0]
+	case common.TARGET_TYPE_CONDITION:
+		message.Condition, err = FormatCondition(target.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid condition:[%v]", c.cfg.Name, err)
+		}
+	default:
+		return nil, common.NotSupportedTargetError(c.cfg.Name, target)
+	}
+	key := target.Keys()[0]
+	item, err := c.send(context.Background(), msg, message, key)
+	return map[string]*common.CallbackResponseItem{key: item}, err
+}
+
+//全量推送，通过所有设备订阅的BroadcastTopic实现，返回消息id
+func (c *FcmClient) BroadcastAll(msg *common.Msg) (string, error) {
+	resultMap, err := c.PushTargetMsg(msg, common.NewTopicTarget(BroadcastTopic))
+	if err != nil {
+		return "", err
+	}
+	return resultMap[BroadcastTopic].RequestId, nil
+}
+
+//发送单条消息，返回key对应的结果
+func (c *FcmClient) send(ctx context.Context, msg *common.Msg, message *FcmMessage, key string) (*common.CallbackResponseItem, error) {
+	item := &common.CallbackResponseItem{
+		MsgId:        msg.Id,
+		Token:        key,
+		DeviceVendor: c.cfg.Name,
+		PackageName:  c.cfg.Package,
+	}
+	accessToken, _, err := c.tokens.Token(ctx)
+	if err != nil {
+		item.Status = common.CALLBACK_STATUS_NEED_RETRY
+		item.Description = err.Error()
+		return item, fmt.Errorf("%s get access token error:[%v]", c.cfg.Name, err)
+	}
+	body, err := json.Marshal(&FcmRequest{Message: message})
+	if err != nil {
+		return item, err
+	}
+	request := &clients.Request{
+		Method: http.MethodPost,
+		URL:    c.sendUrl,
+		Body:   body,
+		Header: []clients.HTTPOption{
+			clients.SetHeader("Content-Type", "application/json"),
+			clients.SetHeader("Authorization", "Bearer "+accessToken),
+		},
+	}
+	resp, err := c.client.DoHttpRequest(ctx, request)
+	if err != nil {
+		item.Status = common.CALLBACK_STATUS_NEED_RETRY
+		item.Description = err.Error()
+		return item, fmt.Errorf("%s push msg error:[%v]", c.cfg.Name, err)
+	}
+	//access token失效，下次发送重新获取，响应不一定是JSON，需在解析前处理
+	if resp.Status == http.StatusUnauthorized {
+		c.tokens.Reset()
+	}
+	var res FcmResponse
+	if err = json.Unmarshal(resp.Body, &res); err != nil {
+		item.Status = common.CALLBACK_STATUS_NEED_RETRY
+		item.Description = string(resp.Body)
+		return item, fmt.Errorf("%s invalid push response status:[%d] body:[%s]", c.cfg.Name, resp.Status, string(resp.Body))
+	}
+	if resp.Status == http.StatusOK {
+		item.Status = common.CALLBACK_STATUS_OK
+		item.RequestId = res.Name[strings.LastIndex(res.Name, "/")+1:]
+		log.WithField("result", res).Tracef("%s send msg", c.cfg.Name)
+		return item, nil
+	}
+	if res.Error == nil {
+		res.Error = &FcmError{Code: resp.Status, Message: string(resp.Body)}
+	}
+	item.Status = formatFcmStatus(res.Error)
+	item.Description = res.Error.Reason() + ": " + res.Error.Message
+	log.WithField("result", res).WithField("token", key).Infof("%s send msg error", c.cfg.Name)
+	//token失效等不需重推的错误只记录在结果中
+	if item.Status == common.CALLBACK_STATUS_NEED_RETRY {
+		return item, fmt.Errorf("%s push msg error:[%s]", c.cfg.Name, item.Description)
+	}
+	return item, nil
+}
+
+func (c *FcmClient) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
+	return nil, nil, nil
+}
+
+func (c *FcmClient) RegisterDeviceToken(deviceId string) (deviceToken string, err error) {
+	return deviceId, nil
+}
+
+func formatFcmStatus(fcmErr *FcmError) int64 {
+	switch fcmErr.Reason() {
+	case "UNREGISTERED": //应用卸载或token过期
+		return common.CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN
+	case "SENDER_ID_MISMATCH":
+		return common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN
+	case "QUOTA_EXCEEDED":
+		return common.CALLBACK_STATUS_PUSH_RATE_LIMIT
+	case "UNAVAILABLE", "INTERNAL":
+		return common.CALLBACK_STATUS_NEED_RETRY
+	case "UNAUTHENTICATED", "THIRD_PARTY_AUTH_ERROR": //access token或APNs、web push凭证失效，与设备token无关
+		return common.CALLBACK_STATUS_NEED_RETRY
+	}
+	if fcmErr.Code == http.StatusUnauthorized || fcmErr.Code >= 500 {
+		return common.CALLBACK_STATUS_NEED_RETRY
+	}
+	return int64(fcmErr.Code)
+}
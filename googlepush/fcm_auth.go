@@ -0,0 +1,167 @@
+package googlepush
+
+import (
+	"push_sdks/clients"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_AUTH_URL = "https://oauth2.googleapis.com/token"
+	FCM_AUTH_SCOPE   = "https://www.googleapis.com/auth/firebase.messaging"
+	JWT_GRANT_TYPE   = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+//google服务账号json配置
+type ServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectId    string `json:"project_id"`
+	PrivateKeyId string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenUri     string `json:"token_uri"`
+}
+
+func LoadServiceAccount(file string) (*ServiceAccount, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	account := &ServiceAccount{}
+	if err = json.Unmarshal(data, account); err != nil {
+		return nil, err
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, errors.New("service account missing client_email or private_key")
+	}
+	return account, nil
+}
+
+type oauthTokenResult struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+//用服务账号签名JWT换取OAuth access token，过期前1分钟刷新
+type tokenSource struct {
+	account  *ServiceAccount
+	key      *rsa.PrivateKey
+	authUrl  string
+	client   *clients.HTTPClient
+	lock     sync.Mutex
+	token    string
+	expireAt time.Time
+}
+
+func newTokenSource(account *ServiceAccount, authUrl string, client *clients.HTTPClient) (*tokenSource, error) {
+	key, err := parsePrivateKey(account.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if authUrl == "" {
+		authUrl = account.TokenUri
+	}
+	if authUrl == "" {
+		authUrl = DEFAULT_AUTH_URL
+	}
+	return &tokenSource{account: account, key: key, authUrl: authUrl, client: client}, nil
+}
+
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid private key pem")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key error:[%v]", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not rsa")
+	}
+	return key, nil
+}
+
+//返回access token和剩余有效秒数
+func (t *tokenSource) Token(ctx context.Context) (string, int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	now := time.Now()
+	if t.token != "" && now.Add(time.Minute).Before(t.expireAt) {
+		return t.token, int(t.expireAt.Sub(now).Seconds()), nil
+	}
+	assertion, err := t.signJwt(now)
+	if err != nil {
+		return "", 0, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", JWT_GRANT_TYPE)
+	form.Set("assertion", assertion)
+	request := &clients.Request{
+		Method: http.MethodPost,
+		URL:    t.authUrl,
+		Body:   []byte(form.Encode()),
+		Header: []clients.HTTPOption{clients.SetHeader("Content-Type", "application/x-www-form-urlencoded")},
+	}
+	resp, err := t.client.DoHttpRequest(ctx, request)
+	if err != nil {
+		return "", 0, err
+	}
+	var result oauthTokenResult
+	if err = json.Unmarshal(resp.Body, &result); err != nil {
+		return "", 0, fmt.Errorf("invalid oauth token response status:[%d] body:[%s]", resp.Status, string(resp.Body))
+	}
+	if resp.Status != http.StatusOK || result.AccessToken == "" {
+		return "", 0, fmt.Errorf("get oauth token error:[%s] %s", result.Error, result.ErrorDescription)
+	}
+	t.token = result.AccessToken
+	t.expireAt = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return t.token, result.ExpiresIn, nil
+}
+
+//RS256签名的服务账号JWT
+func (t *tokenSource) signJwt(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": t.account.PrivateKeyId})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   t.account.ClientEmail,
+		"scope": FCM_AUTH_SCOPE,
+		"aud":   t.authUrl,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hashed := sha256.Sum256([]byte(unsigned))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sign), nil
+}
+
+//清除缓存的access token
+func (t *tokenSource) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.token = ""
+}
@@ -0,0 +1,104 @@
+package googlepush
+
+import (
+	"push_sdks/common"
+)
+
+//FCM HTTP v1 消息格式 https://firebase.google.com/docs/reference/fcm/rest/v1/projects.messages
+
+type FcmRequest struct {
+	ValidateOnly bool        `json:"validate_only,omitempty"`
+	Message      *FcmMessage `json:"message"`
+}
+
+type FcmMessage struct {
+	Name         string            `json:"name,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+	Notification *FcmNotification  `json:"notification,omitempty"`
+	Android      *FcmAndroidConfig `json:"android,omitempty"`
+	Apns         *FcmApnsConfig    `json:"apns,omitempty"`
+	Webpush      *FcmWebpushConfig `json:"webpush,omitempty"`
+	Token        string            `json:"token,omitempty"`
+	Topic        string            `json:"topic,omitempty"`
+	Condition    string            `json:"condition,omitempty"`
+}
+
+type FcmNotification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+	Image string `json:"image,omitempty"`
+}
+
+type FcmAndroidConfig struct {
+	CollapseKey  string                  `json:"collapse_key,omitempty"`
+	Priority     string                  `json:"priority,omitempty"`
+	Ttl          string                  `json:"ttl,omitempty"` //秒数加s后缀，如"3600s"
+	Data         map[string]string       `json:"data,omitempty"`
+	Notification *FcmAndroidNotification `json:"notification,omitempty"`
+}
+
+type FcmAndroidNotification struct {
+	Title             string `json:"title,omitempty"`
+	Body              string `json:"body,omitempty"`
+	Icon              string `json:"icon,omitempty"`
+	Color             string `json:"color,omitempty"`
+	Sound             string `json:"sound,omitempty"`
+	Tag               string `json:"tag,omitempty"`
+	ClickAction       string `json:"click_action,omitempty"`
+	ChannelId         string `json:"channel_id,omitempty"`
+	Image             string `json:"image,omitempty"`
+	Visibility        string `json:"visibility,omitempty"`
+	NotificationCount *int   `json:"notification_count,omitempty"`
+}
+
+type FcmApnsConfig struct {
+	Headers map[string]string      `json:"headers,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+type FcmWebpushConfig struct {
+	Headers      map[string]string       `json:"headers,omitempty"`
+	Data         map[string]string       `json:"data,omitempty"`
+	Notification *FcmWebpushNotification `json:"notification,omitempty"`
+	FcmOptions   *FcmWebpushOptions      `json:"fcm_options,omitempty"`
+}
+
+type FcmWebpushNotification struct {
+	Title   string                  `json:"title,omitempty"`
+	Body    string                  `json:"body,omitempty"`
+	Icon    string                  `json:"icon,omitempty"`
+	Image   string                  `json:"image,omitempty"`
+	Tag     string                  `json:"tag,omitempty"`
+	Actions []*common.WebPushAction `json:"actions,omitempty"`
+}
+
+type FcmWebpushOptions struct {
+	Link string `json:"link,omitempty"`
+}
+
+type FcmResponse struct {
+	Name  string    `json:"name"` //projects/{project_id}/messages/{message_id}
+	Error *FcmError `json:"error,omitempty"`
+}
+
+type FcmError struct {
+	Code    int              `json:"code"`
+	Message string           `json:"message"`
+	Status  string           `json:"status"`
+	Details []FcmErrorDetail `json:"details"`
+}
+
+type FcmErrorDetail struct {
+	Type      string `json:"@type"`
+	ErrorCode string `json:"errorCode"`
+}
+
+// FCM错误码，优先取details中的errorCode
+func (e *FcmError) Reason() string {
+	for _, detail := range e.Details {
+		if detail.ErrorCode != "" {
+			return detail.ErrorCode
+		}
+	}
+	return e.Status
+}
@@ -0,0 +1,237 @@
+package googlepush
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"push_sdks/common"
+	"push_sdks/config"
+
+	"firebase.google.com/go/messaging"
+)
+
+func newFcmStub(t *testing.T) (*FcmClient, *int32, func()) {
+	var tokenRequests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+		r.ParseForm()
+		if r.Form.Get("grant_type") != JWT_GRANT_TYPE || strings.Count(r.Form.Get("assertion"), ".") != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"access","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1/projects/demo/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":401,"message":"invalid credentials","status":"UNAUTHENTICATED"}}`))
+			return
+		}
+		var req FcmRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if strings.HasPrefix(req.Message.Token, "bad") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"not registered","status":"NOT_FOUND","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`))
+			return
+		}
+		w.Write([]byte(`{"name":"projects/demo/messages/` + req.Message.Token + req.Message.Topic + `"}`))
+	})
+	mux.HandleFunc("/iid/v1:batchAdd", func(w http.ResponseWriter, r *http.Request) {
+		var req iidRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("access_token_auth") != "true" || req.To != "/topics/news" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		results := make([]map[string]string, len(req.Tokens))
+		for i, token := range req.Tokens {
+			results[i] = map[string]string{}
+			if strings.HasPrefix(token, "bad") {
+				results[i]["error"] = "NOT_FOUND"
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	})
+	server := httptest.NewServer(mux)
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: mustMarshalPKCS8(t, key)})
+	account, _ := json.Marshal(&ServiceAccount{ProjectId: "demo", ClientEmail: "sa@demo.iam", PrivateKey: string(keyPem)})
+	file, _ := ioutil.TempFile("", "fcm_account")
+	file.Write(account)
+	file.Close()
+
+	client, err := NewFcmClient(config.PushServerCfg{
+		Name:            "fcm",
+		ExtraConfigFile: file.Name(),
+		AuthUrl:         server.URL + "/token",
+		PushUrl:         server.URL,
+		TopicUrl:        server.URL + "/iid/v1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &tokenRequests, func() {
+		server.Close()
+		os.Remove(file.Name())
+	}
+}
+
+func mustMarshalPKCS8(t *testing.T, key *rsa.PrivateKey) []byte {
+	data, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestFcmClientPushMsg(t *testing.T) {
+	client, tokenRequests, closer := newFcmStub(t)
+	defer closer()
+
+	tokens := []string{"a", "b", "bad1", "c"}
+	results, err := client.PushMsg(&common.Msg{MsgTitle: "title", MsgBody: "body"}, tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(tokens) {
+		t.Fatalf("expect %d results, got %d", len(tokens), len(results))
+	}
+	if results["a"].Status != common.CALLBACK_STATUS_OK || results["a"].RequestId != "a" {
+		t.Errorf("unexpected result %+v", results["a"])
+	}
+	if results["bad1"].Status != common.CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN {
+		t.Errorf("unexpected result %+v", results["bad1"])
+	}
+	if atomic.LoadInt32(tokenRequests) != 1 {
+		t.Errorf("access token should be cached, got %d requests", *tokenRequests)
+	}
+
+	taskId, err := client.BroadcastAll(&common.Msg{MsgTitle: "title"})
+	if err != nil || taskId != BroadcastTopic {
+		t.Errorf("unexpected broadcast result %s %v", taskId, err)
+	}
+}
+
+func TestFcmClientResetTokenOnUnauthorized(t *testing.T) {
+	client, tokenRequests, closer := newFcmStub(t)
+	defer closer()
+
+	client.tokens.token = "stale"
+	client.tokens.expireAt = time.Now().Add(time.Hour)
+	results, err := client.PushMsg(&common.Msg{MsgTitle: "title"}, []string{"a"})
+	if err == nil || results["a"].Status != common.CALLBACK_STATUS_NEED_RETRY {
+		t.Errorf("stale access token should be retried, got %+v %v", results["a"], err)
+	}
+	results, err = client.PushMsg(&common.Msg{MsgTitle: "title"}, []string{"a"})
+	if err != nil || results["a"].Status != common.CALLBACK_STATUS_OK {
+		t.Errorf("unexpected result %+v %v", results["a"], err)
+	}
+	if atomic.LoadInt32(tokenRequests) != 1 {
+		t.Errorf("access token should be fetched again after 401, got %d requests", *tokenRequests)
+	}
+}
+
+func TestFcmFormatMsg(t *testing.T) {
+	count := 2
+	message := formatFcmMessage(&common.Msg{
+		MsgTitle:  "title",
+		MsgBody:   "body",
+		MsgAction: "action",
+		ChannelID: "news",
+		Ios:       &common.IosOption{CollapseId: "c1"},
+		Fcm: &common.FcmOption{
+			Data:              map[string]string{"k": "v"},
+			Priority:          common.DeliveryPriorityHigh,
+			Ttl:               3600,
+			Color:             "#ff0000",
+			NotificationCount: &count,
+			WebpushLink:       "https://example.com",
+			WebpushActions:    []*common.WebPushAction{{Action: "open", Title: "Open"}},
+		},
+	})
+	android := message.Android
+	if android.Ttl != "3600s" || android.Priority != "HIGH" || android.Notification.ChannelId != "news" ||
+		*android.Notification.NotificationCount != 2 {
+		t.Errorf("unexpected android config %+v %+v", android, android.Notification)
+	}
+	if message.Data["appData"] != "action" || message.Data["k"] != "v" || android.Data != nil || message.Webpush.Data != nil {
+		t.Errorf("unexpected message data %+v", message.Data)
+	}
+	if message.Apns.Headers["apns-collapse-id"] != "c1" || message.Apns.Headers["apns-push-type"] != "alert" || message.Apns.Payload["aps"] == nil {
+		t.Errorf("unexpected apns config %+v", message.Apns)
+	}
+	if message.Webpush.Headers["TTL"] != "3600" || message.Webpush.FcmOptions.Link != "https://example.com" || len(message.Webpush.Notification.Actions) != 1 {
+		t.Errorf("unexpected webpush config %+v", message.Webpush)
+	}
+}
+
+func TestClientFormatMsg(t *testing.T) {
+	c := &Client{cfg: &config.PushServerCfg{Name: "google"}}
+	message, err := c.formatMsg(&common.Msg{
+		MsgTitle:  "title",
+		MsgBody:   "body",
+		MsgAction: "action",
+		ChannelID: "news",
+		Ios:       &common.IosOption{Sound: "ring.caf"},
+		Fcm: &common.FcmOption{
+			Data:        map[string]string{"k": "v"},
+			Priority:    common.DeliveryPriorityHigh,
+			Ttl:         3600,
+			CollapseKey: "c1",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	android := message.Android
+	if android.Priority != "high" || android.CollapseKey != "c1" || android.TTL == nil || *android.TTL != time.Hour ||
+		android.Notification.ChannelID != "news" || android.Notification.Visibility != messaging.VisibilityPrivate {
+		t.Errorf("unexpected android config %+v %+v", android, android.Notification)
+	}
+	if message.Data["appData"] != "action" || message.Data["k"] != "v" {
+		t.Errorf("unexpected message data %+v", message.Data)
+	}
+	if message.APNS == nil || message.APNS.Payload.Aps.Sound != "ring.caf" {
+		t.Errorf("unexpected apns config %+v", message.APNS)
+	}
+}
+
+func TestFcmSubscribeTopic(t *testing.T) {
+	client, _, closer := newFcmStub(t)
+	defer closer()
+
+	var tokens []string
+	for i := 0; i < MaxTopicBatchSize+10; i++ {
+		tokens = append(tokens, fmt.Sprintf("token%d", i))
+	}
+	tokens = append(tokens, "bad1")
+	results, err := client.SubscribeTopic(context.Background(), "/topics/news", tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(tokens) || results["token1005"].Status != common.CALLBACK_STATUS_OK {
+		t.Errorf("unexpected results count %d", len(results))
+	}
+	if results["bad1"].Status != common.CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN {
+		t.Errorf("unexpected result %+v", results["bad1"])
+	}
+	if _, err = client.SubscribeTopic(context.Background(), "bad topic", tokens); err == nil {
+		t.Errorf("invalid topic name should be rejected")
+	}
+}
@@ -0,0 +1,161 @@
+package googlepush
+
+import (
+	"push_sdks/clients"
+	"push_sdks/common"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"firebase.google.com/go/messaging"
+	log "github.com/sirupsen/logrus"
+)
+
+//FCM单次订阅、取消订阅最多1000个token
+const MaxTopicBatchSize = 1000
+
+//topic订阅管理接口默认地址，可通过TopicUrl配置覆盖
+const DEFAULT_TOPIC_URL = "https://iid.googleapis.com/iid/v1"
+
+const (
+	TOPIC_SUBSCRIBE   = "batchAdd"
+	TOPIC_UNSUBSCRIBE = "batchRemove"
+)
+
+var topicNamePattern = regexp.MustCompile(`^[a-zA-Z0-9-_.~%]+$`)
+
+//批量订阅操作，返回失败token的下标和原因
+type topicBatchFunc func(ctx context.Context, topic string, tokens []string) (map[int]string, error)
+
+type iidRequest struct {
+	To     string   `json:"to"`
+	Tokens []string `json:"registration_tokens"`
+}
+
+type iidResponse struct {
+	Results []struct {
+		Error string `json:"error"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+//按MaxTopicBatchSize分批执行，失败原因转换为推送状态
+func manageTopic(ctx context.Context, vendor, packageName, topic string, tokens []string, batchFunc topicBatchFunc) (map[string]*common.CallbackResponseItem, error) {
+	topic = strings.TrimPrefix(topic, "/topics/")
+	if !topicNamePattern.MatchString(topic) {
+		return nil, fmt.Errorf("%s invalid topic name:[%s]", vendor, topic)
+	}
+	return common.ManageTopic(ctx, vendor, packageName, topic, tokens, MaxTopicBatchSize, func(ctx context.Context, tokens []string) (string, map[int]*common.TopicTokenError, error) {
+		reasons, err := batchFunc(ctx, topic, tokens)
+		if err != nil {
+			log.WithError(err).WithField("topic", topic).Warnf("%s manage topic error", vendor)
+			return "", nil, err
+		}
+		failed := make(map[int]*common.TopicTokenError, len(reasons))
+		for i, reason := range reasons {
+			failed[i] = &common.TopicTokenError{Status: formatTopicStatus(reason), Reason: reason}
+		}
+		return "", failed, nil
+	})
+}
+
+//订阅、取消订阅的错误原因，兼容iid接口错误码和firebase sdk的错误描述
+func formatTopicStatus(reason string) int64 {
+	switch {
+	case reason == "NOT_FOUND" || strings.Contains(reason, "registration-token-not-registered"):
+		return common.CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN
+	case reason == "INVALID_ARGUMENT" || strings.Contains(reason, "invalid-argument"):
+		return common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN
+	case reason == "RESOURCE_EXHAUSTED" || strings.Contains(reason, "quota-exceeded"):
+		return common.CALLBACK_STATUS_PUSH_RATE_LIMIT
+	case reason == "TOO_MANY_TOPICS" || strings.Contains(reason, "too-many-topics"):
+		return common.CALLBACK_STATUS_TOO_MANY_TOPICS
+	case reason == "INTERNAL" || reason == "UNAVAILABLE" || strings.Contains(reason, "internal-error"):
+		return common.CALLBACK_STATUS_NEED_RETRY
+	}
+	return common.UNKONW
+}
+
+//SubscribeTopic 订阅topic，返回每个token的结果
+func (c *FcmClient) SubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	return manageTopic(ctx, c.cfg.Name, c.cfg.Package, topic, tokens, c.topicBatch(TOPIC_SUBSCRIBE))
+}
+
+//UnsubscribeTopic 取消订阅topic，返回每个token的结果
+func (c *FcmClient) UnsubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	return manageTopic(ctx, c.cfg.Name, c.cfg.Package, topic, tokens, c.topicBatch(TOPIC_UNSUBSCRIBE))
+}
+
+func (c *FcmClient) topicBatch(operation string) topicBatchFunc {
+	return func(ctx context.Context, topic string, tokens []string) (map[int]string, error) {
+		accessToken, _, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(&iidRequest{To: "/topics/" + topic, Tokens: tokens})
+		if err != nil {
+			return nil, err
+		}
+		request := &clients.Request{
+			Method: http.MethodPost,
+			URL:    c.topicUrl + ":" + operation,
+			Body:   body,
+			Header: []clients.HTTPOption{
+				clients.SetHeader("Content-Type", "application/json"),
+				clients.SetHeader("Authorization", "Bearer "+accessToken),
+				clients.SetHeader("access_token_auth", "true"),
+			},
+		}
+		resp, err := c.client.DoHttpRequest(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Status == http.StatusUnauthorized {
+			c.tokens.Reset()
+		}
+		var res iidResponse
+		if err = json.Unmarshal(resp.Body, &res); err != nil || resp.Status != http.StatusOK {
+			return nil, fmt.Errorf("status:[%d] body:[%s]", resp.Status, string(resp.Body))
+		}
+		if len(res.Results) != len(tokens) {
+			return nil, fmt.Errorf("unexpected results count %d, expect %d", len(res.Results), len(tokens))
+		}
+		failed := map[int]string{}
+		for i, result := range res.Results {
+			if result.Error != "" {
+				failed[i] = result.Error
+			}
+		}
+		return failed, nil
+	}
+}
+
+//SubscribeTopic 订阅topic，返回每个token的结果
+func (c *Client) SubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	return manageTopic(ctx, c.cfg.Name, c.cfg.Package, topic, tokens, func(ctx context.Context, topic string, tokens []string) (map[int]string, error) {
+		res, err := c.msgClient.SubscribeToTopic(ctx, tokens, topic)
+		return topicErrors(res), err
+	})
+}
+
+//UnsubscribeTopic 取消订阅topic，返回每个token的结果
+func (c *Client) UnsubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	return manageTopic(ctx, c.cfg.Name, c.cfg.Package, topic, tokens, func(ctx context.Context, topic string, tokens []string) (map[int]string, error) {
+		res, err := c.msgClient.UnsubscribeFromTopic(ctx, tokens, topic)
+		return topicErrors(res), err
+	})
+}
+
+func topicErrors(res *messaging.TopicManagementResponse) map[int]string {
+	failed := map[int]string{}
+	if res == nil {
+		return failed
+	}
+	for _, info := range res.Errors {
+		failed[info.Index] = info.Reason
+	}
+	return failed
+}
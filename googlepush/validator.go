@@ -0,0 +1,50 @@
+package googlepush
+
+import (
+	"push_sdks/common"
+	"regexp"
+	"strconv"
+)
+
+const (
+	MAX_MESSAGE_BYTES = 4096
+	MAX_TTL_SECONDS   = 28 * 24 * 3600
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+//FCM限制：消息4KB，ttl最长28天，优先级、颜色、web链接格式
+var MsgRules = common.RuleSet{
+	common.MaxBytesRule("message", MAX_MESSAGE_BYTES, func(msg *common.Msg) (interface{}, error) {
+		message := formatFcmMessage(msg)
+		//APNs部分由applepush规则单独限制
+		message.Apns = nil
+		return message, nil
+	}),
+	common.EnumRule("android.priority", func(msg *common.Msg) string { return msg.GetFcm().Priority },
+		common.DeliveryPriorityHigh, common.DeliveryPriorityNormal),
+	common.RangeRule("android.ttl", 0, MAX_TTL_SECONDS, func(msg *common.Msg) (float64, bool) {
+		return float64(msg.GetFcm().Ttl), true
+	}),
+	common.PatternRule("android.notification.color", colorPattern, func(msg *common.Msg) string { return msg.GetFcm().Color }),
+	common.UrlRule("webpush.fcm_options.link", func(msg *common.Msg) string { return msg.GetFcm().WebpushLink }, "https"),
+	common.UrlRule("notification.image", common.MsgImgUrlField, "http", "https"),
+	webpushActionRule,
+}
+
+func webpushActionRule(msg *common.Msg) *common.Violation {
+	for i, action := range msg.GetFcm().WebpushActions {
+		if action == nil || action.Action == "" {
+			return &common.Violation{Field: "webpush.notification.actions[" + strconv.Itoa(i) + "].action", Rule: common.RULE_REQUIRED, Message: "must not be empty"}
+		}
+	}
+	return nil
+}
+
+func (c *FcmClient) ValidateMsg(msg *common.Msg) []*common.Violation {
+	return MsgRules.Validate(c.cfg.Name, msg)
+}
+
+func (c *Client) ValidateMsg(msg *common.Msg) []*common.Violation {
+	return MsgRules.Validate(c.cfg.Name, msg)
+}
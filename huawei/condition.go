@@ -0,0 +1,24 @@
+package huawei
+
+import (
+	"fmt"
+
+	model "push_sdks/common"
+)
+
+const (
+	// the max number of topics in a condition expression
+	MaxConditionTopics = 5
+)
+
+// FormatCondition compiles the condition to the HMS expression syntax
+// e.g. 'TopicA' in topics && ('TopicB' in topics || 'TopicC' in topics)
+func FormatCondition(condition *model.Condition) (string, error) {
+	if err := condition.Validate(); err != nil {
+		return "", err
+	}
+	if topics := condition.Topics(); len(topics) > MaxConditionTopics {
+		return "", fmt.Errorf("condition supports at most %d topics, got %d", MaxConditionTopics, len(topics))
+	}
+	return condition.Expression(), nil
+}
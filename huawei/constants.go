@@ -2,9 +2,17 @@ package huawei
 
 const (
 	//the parameters of the formats below are endpoint and appId
-	SendMessageFmt = "%s/v1/%s/messages:send"
+	SendMessageFmt   = "%s/v1/%s/messages:send"
+	RevokeMessageFmt = "%s/v1/%s/messages:revoke"
+
+	SubscribeTopicFmt   = "%s/v1/%s/topic:subscribe"
+	UnsubscribeTopicFmt = "%s/v1/%s/topic:unsubscribe"
+	ListTopicFmt        = "%s/v1/%s/topic:list"
 )
 
+// the max number of tokens in one subscribe/unsubscribe request
+const MaxTopicTokens = 1000
+
 const (
 	// unspecified visibility
 	VisibilityUnspecified = "VISIBILITY_UNSPECIFIED"
@@ -33,6 +33,40 @@ func (c *HttpPushClient) SendMessage(ctx context.Context, msgRequest *model.Mess
 	return result, err
 }
 
+// RevokeMessage revokes a sent message by message id
+// If tokens is empty, the message is revoked for all target devices
+func (c *HttpPushClient) RevokeMessage(ctx context.Context, messageId string, tokens []string) (*model.MessageResponse, error) {
+	result := &model.MessageResponse{}
+
+	params := map[string]interface{}{
+		"message_id": messageId,
+	}
+	//主题、条件消息撤回不带token
+	if len(tokens) > 0 {
+		params["token"] = tokens
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &clients.Request{
+		Method: http.MethodPost,
+		URL:    fmt.Sprintf(RevokeMessageFmt, c.endpoint, c.appId),
+		Body:   body,
+		Header: []clients.HTTPOption{
+			clients.SetHeader("Content-Type", "application/json;charset=utf-8"),
+			clients.SetHeader("Authorization", "Bearer "+c.token),
+		},
+	}
+
+	err = c.executeApiOperation(ctx, request, result)
+	if err != nil {
+		return result, err
+	}
+	return result, err
+}
+
 func (c *HttpPushClient) getSendMsgRequest(msgRequest *model.MessageRequest) (*clients.Request, error) {
 	body, err := json.Marshal(msgRequest)
 	if err != nil {
@@ -9,15 +9,25 @@ import (
 	"push_sdks/common"
 	model "push_sdks/common"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
 
+//华为通道推送的设备类型，webpush、APNs和快应用需要单独配置推送服务
+const (
+	VENDOR_HUAWEI          = "huawei"
+	VENDOR_HUAWEI_WEBPUSH  = "huawei_webpush"
+	VENDOR_HUAWEI_APNS     = "huawei_apns"
+	VENDOR_HUAWEI_QUICKAPP = "huawei_quickapp"
+)
+
 type HuaweiClient struct {
 	cfg    *config.PushServerCfg
 	client *HttpPushClient
@@ -29,9 +39,13 @@ type tokenResponse struct {
 	TokenType   string `json:"token_type"`
 }
 
+//每个配置使用独立的HttpPushClient，不同应用的access token互不影响
 func NewClient(conf config.PushServerCfg) (*HuaweiClient, error) {
-	pushClient = GetPushClient(&conf)
-	return &HuaweiClient{client: pushClient, cfg: &conf}, nil
+	httpClient, err := NewHttpClient(&conf)
+	if err != nil {
+		return nil, fmt.Errorf("%s NewClient error: [%v]", conf.Name, err)
+	}
+	return &HuaweiClient{client: httpClient, cfg: &conf}, nil
 }
 
 func (c *HuaweiClient) Name() string {
@@ -51,13 +65,36 @@ func (c *HuaweiClient) GetToken() (string, int, error) {
 }
 
 func (c *HuaweiClient) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
-	msgRequest, err := c.getMsgRequest(msg, tokens)
+	return c.PushTargetMsg(msg, common.NewTokenTarget(tokens))
+}
+
+//按目标类型推送，支持token、单个topic和条件表达式
+func (c *HuaweiClient) PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	msgRequest, err := c.getMsgRequest(msg)
 	if err != nil {
 		log.Errorf("Failed to get message request! Error is %s\n", err.Error())
 		return nil, err
 	}
+	switch target.Type {
+	case common.TARGET_TYPE_TOKEN:
+		msgRequest.Message.Token = target.Values
+	case common.TARGET_TYPE_TOPIC:
+		if len(target.Values) != 1 {
+			return nil, fmt.Errorf("%s only one topic can be pushed at a time", c.cfg.Name)
+		}
+		msgRequest.Message.Topic = target.Values[0]
+	case common.TARGET_TYPE_CONDITION:
+		condition, err := FormatCondition(target.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid condition:[%v]", c.cfg.Name, err)
+		}
+		msgRequest.Message.Condition = condition
+	default:
+		return nil, common.NotSupportedTargetError(c.cfg.Name, target)
+	}
+	tokens := target.Keys()
 
-	resp, err := client.SendMessage(context.Background(), msgRequest)
+	resp, err := c.client.SendMessage(context.Background(), msgRequest)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"msg":    msg,
@@ -93,10 +130,34 @@ func (c *HuaweiClient) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap m
 	return failsInfoMap, nil
 }
 
-func (c *HuaweiClient) getMsgRequest(msg *common.Msg, tokens []string) (*model.MessageRequest, error) {
+//撤回已发送的消息，RequestId为推送返回的消息id，按token发送的消息需带上发送时的token
+func (c *HuaweiClient) Recall(ctx context.Context, requests ...*common.RecallRequest) ([]*common.RecallResult, error) {
+	list := make([]*common.RecallResult, 0, len(requests))
+	for _, request := range requests {
+		item := &common.RecallResult{DeviceVendor: c.cfg.Name, PackageName: c.cfg.Package, RequestId: request.RequestId}
+		resp, err := c.client.RevokeMessage(ctx, request.RequestId, request.Tokens)
+		if err != nil {
+			item.Description = err.Error()
+		} else {
+			item.Success = resp.Code == common.Success
+			item.Description = resp.Msg
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func (c *HuaweiClient) getMsgRequest(msg *common.Msg) (*model.MessageRequest, error) {
+	switch strings.ToLower(c.cfg.Name) {
+	case VENDOR_HUAWEI_WEBPUSH:
+		return c.getWebPushMsgRequest(msg), nil
+	case VENDOR_HUAWEI_APNS:
+		return c.getApnsMsgRequest(msg), nil
+	case VENDOR_HUAWEI_QUICKAPP:
+		return c.getQuickAppMsgRequest(msg)
+	}
 	msgRequest := model.NewNotificationMsgRequest()
 	msgRequest.Message.Data = "msgRequest.Message.Data"
-	msgRequest.Message.Token = tokens
 	msgRequest.Message.Android = model.GetDefaultAndroid()
 	msgRequest.Message.Android.Notification = model.GetDefaultAndroidNotification()
 	msgRequest.Message.Android.Data = "msgRequest.Message.Android.Data"
@@ -119,14 +180,29 @@ type callbackRequest struct {
 	Data []*common.CallbackResponseItem `json:"statuses"`
 }
 
+//校验回执，华为回执在控制台配置，token可放在Authorization头或回执地址中
+func (c *HuaweiClient) VerifyCallback(r *http.Request) error {
+	if c.cfg.CallbackToken == "" {
+		return nil
+	}
+	authorization := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if authorization != "" {
+		if subtle.ConstantTimeCompare([]byte(authorization), []byte(c.cfg.CallbackToken)) != 1 {
+			return &common.CallbackAuthError{DeviceVendor: c.cfg.Name, Reason: "invalid authorization header"}
+		}
+		return nil
+	}
+	return common.VerifyCallbackToken(r, c.cfg.Name, c.cfg.CallbackToken)
+}
+
 func (c *HuaweiClient) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
 	res := map[string]interface{}{"errno": 0, "errmsg": "success"}
 	var reciver callbackRequest
 	requestBody, err := ioutil.ReadAll(r.Body)
 	log.WithFields(log.Fields{
 		"deviceVendor": c.cfg.Name,
-		"url":          r.URL.RequestURI(),
-		"header":       r.Header,
+		"url":          common.RedactUri(r),
+		"header":       common.RedactHeader(r),
 	}).Tracef("callback")
 	err = json.Unmarshal(requestBody, &reciver)
 	if err != nil {
@@ -141,6 +217,10 @@ func (c *HuaweiClient) PushReciver(r *http.Request) (*common.CallbackResponse, m
 		}
 		status := formatStatus(item.Status)
 		item.Status = status
+		item.EventType = common.EVENT_TYPE_FAILED
+		if status == common.CALLBACK_STATUS_OK {
+			item.EventType = common.EVENT_TYPE_DELIVERED
+		}
 		callbackResponse.Data = append(callbackResponse.Data, item)
 	}
 	return &callbackResponse, res, nil
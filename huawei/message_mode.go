@@ -0,0 +1,112 @@
+package huawei
+
+import (
+	"push_sdks/applepush"
+	"push_sdks/common"
+	model "push_sdks/common"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+type quickAppData struct {
+	PushType int              `json:"pushtype"` //0通知栏消息
+	PushBody quickAppPushBody `json:"pushbody"`
+}
+
+type quickAppPushBody struct {
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Page        string            `json:"page"`
+	Params      map[string]string `json:"params,omitempty"`
+}
+
+//web浏览器通知，图标、链接和操作按钮使用FcmOption中的Webpush字段
+func (c *HuaweiClient) getWebPushMsgRequest(msg *common.Msg) *model.MessageRequest {
+	option := msg.GetFcm()
+	msgRequest := model.NewNotificationMsgRequest()
+	msgRequest.Message.Notification = &model.Notification{Title: msg.MsgTitle, Body: msg.MsgBody, Image: msg.ImgUrl}
+	msgRequest.Message.WebPush = &model.WebPushConfig{
+		Data: msg.MsgAction,
+		Notification: &model.WebPushNotification{
+			Title:     msg.MsgTitle,
+			Body:      msg.MsgBody,
+			Icon:      option.WebpushIcon,
+			Image:     msg.ImgUrl,
+			Tag:       option.Tag,
+			Actions:   option.WebpushActions,
+			Dir:       DirAuto,
+			Timestamp: time.Now().Unix(),
+		},
+	}
+	if option.WebpushLink != "" {
+		msgRequest.Message.WebPush.HmsOptions = &model.HmsWebPushOption{Link: option.WebpushLink}
+	}
+	if option.Ttl > 0 {
+		msgRequest.Message.WebPush.Headers = &model.WebPushHeaders{TTL: strconv.FormatInt(option.Ttl, 10)}
+	}
+	return msgRequest
+}
+
+//通过华为通道推送到iOS设备，payload与applepush一致，测试配置推送到开发环境
+func (c *HuaweiClient) getApnsMsgRequest(msg *common.Msg) *model.MessageRequest {
+	msgRequest := model.NewNotificationMsgRequest()
+	msgRequest.Message.Notification = nil
+	notification := applepush.BuildNotification(msg, "")
+	targetUserType := model.TargetUserTypeFormal
+	if c.cfg.TestMod {
+		targetUserType = model.TargetUserTypeTest
+	}
+	if msg.GetIos().PushType == common.IOS_PUSH_TYPE_VOIP {
+		targetUserType = model.TargetUserTypeVoIP
+	}
+	msgRequest.Message.Apns = &model.Apns{
+		HmsOptions: &model.ApnsHmsOptions{TargetUserType: targetUserType},
+		Headers: &model.ApnsHeaders{
+			ApnsPriority:   strconv.Itoa(notification.Priority),
+			ApnsCollapseId: notification.CollapseID,
+		},
+		Payload: applepush.BuildPayload(msg),
+	}
+	if !notification.Expiration.IsZero() {
+		msgRequest.Message.Apns.Headers.ApnsExpiration = notification.Expiration.Unix()
+	}
+	return msgRequest
+}
+
+//快应用通知栏消息，测试配置推送到开发态快应用
+func (c *HuaweiClient) getQuickAppMsgRequest(msg *common.Msg) (*model.MessageRequest, error) {
+	option := msg.QuickApp
+	if option == nil {
+		option = &common.QuickAppOption{}
+	}
+	body := quickAppPushBody{
+		Title:       msg.MsgTitle,
+		Description: msg.MsgBody,
+		Page:        option.Page,
+		Params:      option.Params,
+	}
+	if body.Page == "" {
+		body.Page = "/"
+	}
+	if msg.MsgAction != "" {
+		body.Params = make(map[string]string, len(option.Params)+1)
+		for key, val := range option.Params {
+			body.Params[key] = val
+		}
+		body.Params[applepush.PAYLOAD_KEY_APP_DATA] = msg.MsgAction
+	}
+	data, err := json.Marshal(&quickAppData{PushType: 0, PushBody: body})
+	if err != nil {
+		return nil, err
+	}
+	msgRequest := model.NewNotificationMsgRequest()
+	msgRequest.Message.Notification = nil
+	msgRequest.Message.Data = string(data)
+	msgRequest.Message.Android = model.GetDefaultAndroid()
+	msgRequest.Message.Android.FastAppTarget = FastAppTargetProduct
+	if c.cfg.TestMod {
+		msgRequest.Message.Android.FastAppTarget = FastAppTargetDevelop
+	}
+	return msgRequest, nil
+}
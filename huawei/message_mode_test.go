@@ -0,0 +1,62 @@
+package huawei
+
+import (
+	"testing"
+
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+func TestHuaweiMessageModes(t *testing.T) {
+	msg := &common.Msg{MsgTitle: "title", MsgBody: "body", MsgAction: "action"}
+
+	c := &HuaweiClient{cfg: &config.PushServerCfg{Name: VENDOR_HUAWEI_APNS, TestMod: true}}
+	msgRequest, err := c.getMsgRequest(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgRequest.Message.Token = []string{"token"}
+	if err = ValidateMessage(msgRequest.Message); err != nil {
+		t.Fatal(err)
+	}
+	if msgRequest.Message.Apns.HmsOptions.TargetUserType != common.TargetUserTypeTest || msgRequest.Message.Apns.Payload["aps"] == nil {
+		t.Errorf("unexpected apns message %+v", msgRequest.Message.Apns)
+	}
+	c.cfg.TestMod = false
+	msgRequest, _ = c.getMsgRequest(msg)
+	if msgRequest.Message.Apns.HmsOptions.TargetUserType != common.TargetUserTypeFormal {
+		t.Errorf("formal config should push to formal users")
+	}
+
+	c = &HuaweiClient{cfg: &config.PushServerCfg{Name: VENDOR_HUAWEI_WEBPUSH}}
+	msgRequest, _ = c.getMsgRequest(&common.Msg{MsgTitle: "title", Fcm: &common.FcmOption{WebpushLink: "https://example.com", Ttl: 60}})
+	msgRequest.Message.Token = []string{"token"}
+	if err = ValidateMessage(msgRequest.Message); err != nil {
+		t.Fatal(err)
+	}
+	if msgRequest.Message.WebPush.HmsOptions.Link != "https://example.com" || msgRequest.Message.WebPush.Headers.TTL != "60" {
+		t.Errorf("unexpected webpush message %+v", msgRequest.Message.WebPush)
+	}
+
+	c = &HuaweiClient{cfg: &config.PushServerCfg{Name: VENDOR_HUAWEI_QUICKAPP, TestMod: true}}
+	msgRequest, _ = c.getMsgRequest(msg)
+	msgRequest.Message.Token = []string{"token"}
+	if err = ValidateMessage(msgRequest.Message); err != nil {
+		t.Fatal(err)
+	}
+	if violations := c.ValidateMsg(msg); len(violations) != 0 {
+		t.Errorf("unexpected violations %v", violations)
+	}
+	c.cfg.Name = VENDOR_HUAWEI
+	if violations := c.ValidateMsg(&common.Msg{MsgTitle: "title", MsgBody: "body"}); len(violations) != 0 {
+		t.Errorf("unexpected violations %v", violations)
+	}
+	if violations := c.ValidateMsg(&common.Msg{MsgTitle: "title"}); len(violations) != 1 || violations[0].Field != "body" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+	c.cfg.Name = VENDOR_HUAWEI_QUICKAPP
+	expect := `{"pushtype":0,"pushbody":{"title":"title","description":"body","page":"/","params":{"appData":"action"}}}`
+	if msgRequest.Message.Data != expect || msgRequest.Message.Android.FastAppTarget != FastAppTargetDevelop {
+		t.Errorf("unexpected quick app message %s %d", msgRequest.Message.Data, msgRequest.Message.Android.FastAppTarget)
+	}
+}
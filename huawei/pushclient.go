@@ -71,6 +71,8 @@ func (c *HttpPushClient) executeApiOperation(ctx context.Context, request *clien
 	}
 
 	if retry {
+		//use the refreshed token
+		request.Header = append(request.Header, clients.SetHeader("Authorization", "Bearer "+c.token))
 		err = c.sendHttpRequest(ctx, request, responsePointer)
 		return err
 	}
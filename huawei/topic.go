@@ -0,0 +1,135 @@
+package huawei
+
+import (
+	"push_sdks/clients"
+	"push_sdks/common"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type TopicRequest struct {
+	Topic      string   `json:"topic,omitempty"`
+	TokenArray []string `json:"tokenArray,omitempty"`
+	Token      string   `json:"token,omitempty"`
+}
+
+type TopicResponse struct {
+	Code      string       `json:"code"`
+	Msg       string       `json:"msg"`
+	RequestId string       `json:"requestId"`
+	Result    *TopicResult `json:"result,omitempty"`
+}
+
+type TopicResult struct {
+	SuccessCount int           `json:"successCount"`
+	FailureCount int           `json:"failureCount"`
+	Errors       []*TopicError `json:"errors"`
+}
+
+// TopicError the failed token in a subscribe/unsubscribe request
+type TopicError struct {
+	Index int    `json:"index"`
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+}
+
+type TopicListResponse struct {
+	Code      string       `json:"code"`
+	Msg       string       `json:"msg"`
+	RequestId string       `json:"requestId"`
+	Topics    []*TopicInfo `json:"topics"`
+}
+
+type TopicInfo struct {
+	Name    string `json:"name"`
+	AddDate string `json:"addDate"`
+}
+
+// SubscribeTopic subscribes the tokens to the topic, at most MaxTopicTokens tokens at a time
+func (c *HttpPushClient) SubscribeTopic(ctx context.Context, topic string, tokens []string) (*TopicResponse, error) {
+	result := &TopicResponse{}
+	err := c.topicOperation(ctx, SubscribeTopicFmt, &TopicRequest{Topic: topic, TokenArray: tokens}, result)
+	return result, err
+}
+
+// UnsubscribeTopic unsubscribes the tokens from the topic, at most MaxTopicTokens tokens at a time
+func (c *HttpPushClient) UnsubscribeTopic(ctx context.Context, topic string, tokens []string) (*TopicResponse, error) {
+	result := &TopicResponse{}
+	err := c.topicOperation(ctx, UnsubscribeTopicFmt, &TopicRequest{Topic: topic, TokenArray: tokens}, result)
+	return result, err
+}
+
+// ListTopic lists the topics subscribed by the token
+func (c *HttpPushClient) ListTopic(ctx context.Context, token string) (*TopicListResponse, error) {
+	result := &TopicListResponse{}
+	err := c.topicOperation(ctx, ListTopicFmt, &TopicRequest{Token: token}, result)
+	return result, err
+}
+
+func (c *HttpPushClient) topicOperation(ctx context.Context, urlFmt string, topicRequest *TopicRequest, responsePointer interface{}) error {
+	body, err := json.Marshal(topicRequest)
+	if err != nil {
+		return err
+	}
+	request := &clients.Request{
+		Method: http.MethodPost,
+		URL:    fmt.Sprintf(urlFmt, c.endpoint, c.appId),
+		Body:   body,
+		Header: []clients.HTTPOption{
+			clients.SetHeader("Content-Type", "application/json;charset=utf-8"),
+			clients.SetHeader("Authorization", "Bearer "+c.token),
+		},
+	}
+	return c.executeApiOperation(ctx, request, responsePointer)
+}
+
+//订阅topic，按MaxTopicTokens分批，返回每个token的结果
+func (c *HuaweiClient) SubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	return c.manageTopic(ctx, topic, tokens, c.client.SubscribeTopic)
+}
+
+//取消订阅topic，按MaxTopicTokens分批，返回每个token的结果
+func (c *HuaweiClient) UnsubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	return c.manageTopic(ctx, topic, tokens, c.client.UnsubscribeTopic)
+}
+
+//查询token订阅的topic
+func (c *HuaweiClient) ListTopic(token string) ([]*TopicInfo, error) {
+	resp, err := c.client.ListTopic(context.Background(), token)
+	if err != nil {
+		return nil, fmt.Errorf("%s list topic error:[%v]", c.cfg.Name, err)
+	}
+	if resp.Code != Success {
+		return nil, fmt.Errorf("%s list topic error:[%s] %s", c.cfg.Name, resp.Code, resp.Msg)
+	}
+	return resp.Topics, nil
+}
+
+type topicOperationFunc func(ctx context.Context, topic string, tokens []string) (*TopicResponse, error)
+
+//按MaxTopicTokens分批执行，华为错误码转换为推送状态
+func (c *HuaweiClient) manageTopic(ctx context.Context, topic string, tokens []string, operation topicOperationFunc) (map[string]*common.CallbackResponseItem, error) {
+	return common.ManageTopic(ctx, c.cfg.Name, c.cfg.Package, topic, tokens, MaxTopicTokens, func(ctx context.Context, tokens []string) (string, map[int]*common.TopicTokenError, error) {
+		resp, err := operation(ctx, topic, tokens)
+		if err != nil {
+			return "", nil, err
+		}
+		if resp.Code != Success && (resp.Result == nil || len(resp.Result.Errors) == 0) {
+			return "", nil, fmt.Errorf("code:[%s] msg:[%s]", resp.Code, resp.Msg)
+		}
+		failed := map[int]*common.TopicTokenError{}
+		if resp.Result != nil {
+			for _, item := range resp.Result.Errors {
+				code, parseErr := strconv.ParseInt(item.Code, 10, 64)
+				if parseErr != nil {
+					code = common.UNKONW
+				}
+				failed[item.Index] = &common.TopicTokenError{Status: formatStatus(code), Reason: item.Msg}
+			}
+		}
+		return resp.RequestId, failed, nil
+	})
+}
@@ -0,0 +1,98 @@
+package huawei
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"push_sdks/clients"
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+func TestHuaweiSubscribeTopic(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"fresh","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1/app/topic:subscribe", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.Write([]byte(`{"code":"` + TokenTimeoutErr + `","msg":"token expired"}`))
+			return
+		}
+		var req TopicRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		result := &TopicResult{SuccessCount: len(req.TokenArray)}
+		for i, token := range req.TokenArray {
+			if token == "bad" {
+				result.SuccessCount--
+				result.FailureCount++
+				result.Errors = append(result.Errors, &TopicError{Index: i, Code: "80300007", Msg: "invalid token"})
+			}
+		}
+		json.NewEncoder(w).Encode(&TopicResponse{Code: Success, Msg: "success", RequestId: "req", Result: result})
+	})
+	mux.HandleFunc("/v1/app/topic:list", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"80000000","msg":"success","topics":[{"name":"news","addDate":"2020-01-01"}]}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cfg := &config.PushServerCfg{Name: "huawei", AppId: "app", AppSecret: "secret", AuthUrl: server.URL + "/token", PushUrl: server.URL}
+	authClient, _ := NewAuthClient(cfg)
+	c := &HuaweiClient{cfg: cfg, client: &HttpPushClient{
+		endpoint:   server.URL,
+		appId:      cfg.AppId,
+		token:      "expired",
+		authClient: authClient,
+		client:     clients.NewHTTPClient(),
+	}}
+
+	tokens := []string{"bad"}
+	for i := 0; i < MaxTopicTokens+5; i++ {
+		tokens = append(tokens, fmt.Sprintf("token%d", i))
+	}
+	results, err := c.SubscribeTopic(context.Background(), "news", tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(tokens) || results["token1000"].Status != common.CALLBACK_STATUS_OK {
+		t.Errorf("unexpected results count %d", len(results))
+	}
+	if results["bad"].Status != common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN {
+		t.Errorf("unexpected result %+v", results["bad"])
+	}
+
+	topics, err := c.ListTopic("token1")
+	if err != nil || len(topics) != 1 || topics[0].Name != "news" {
+		t.Errorf("unexpected topics %v %v", topics, err)
+	}
+}
+
+func TestHuaweiPushCondition(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"80000000","msg":"Success","requestId":"req-condition"}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.PushServerCfg{Name: "huawei", AppId: "app", PushUrl: server.URL}
+	c := &HuaweiClient{cfg: cfg, client: &HttpPushClient{
+		endpoint: server.URL,
+		appId:    cfg.AppId,
+		token:    "token",
+		client:   clients.NewHTTPClient(),
+	}}
+	condition := common.AndCondition(common.TopicCondition("news"), common.TopicCondition("sports"))
+	msg := &common.Msg{MsgTitle: "title", MsgBody: "body"}
+	results, err := c.PushTargetMsg(msg, common.NewConditionTarget(condition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := results["condition"]
+	if len(results) != 1 || item == nil || item.RequestId != "req-condition" || item.Status != common.CALLBACK_STATUS_OK {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
@@ -0,0 +1,41 @@
+package huawei
+
+import (
+	"push_sdks/common"
+	"encoding/json"
+	"strings"
+)
+
+//华为消息体最大4KB
+const MaxMessageBytes = 4096
+
+//华为限制：通知栏消息标题和内容必填，其余规则见ValidateMessage
+var MsgRules = common.RuleSet{
+	common.RequiredRule("title", common.MsgTitleField),
+	common.RequiredRule("body", common.MsgBodyField),
+	common.UrlRule("image", common.MsgImgUrlField, "https"),
+}
+
+func (c *HuaweiClient) ValidateMsg(msg *common.Msg) []*common.Violation {
+	//除通用规则外，按发送模式生成请求并执行ValidateMessage
+	var violations []*common.Violation
+	switch strings.ToLower(c.cfg.Name) {
+	case VENDOR_HUAWEI_APNS:
+		//APNs静默推送可以没有标题和内容
+	default:
+		violations = MsgRules.Validate(c.cfg.Name, msg)
+	}
+	msgRequest, err := c.getMsgRequest(msg)
+	if err != nil {
+		return append(violations, &common.Violation{Vendor: c.cfg.Name, Field: "message", Rule: common.RULE_FORMAT, Message: err.Error()})
+	}
+	msgRequest.Message.Token = []string{"token"}
+	if err = ValidateMessage(msgRequest.Message); err != nil {
+		violations = append(violations, &common.Violation{Vendor: c.cfg.Name, Field: "message", Rule: common.RULE_FORMAT, Message: err.Error()})
+	}
+	msgRequest.Message.Token = nil
+	if data, err := json.Marshal(msgRequest.Message); err == nil && len(data) > MaxMessageBytes {
+		violations = append(violations, &common.Violation{Vendor: c.cfg.Name, Field: "message", Rule: common.RULE_MAX_BYTES, Message: "message size exceeds 4096 bytes"})
+	}
+	return violations
+}
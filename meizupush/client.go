@@ -38,27 +38,27 @@ func (c *Client) GetToken() (token string, expire_in int, err error) {
 }
 
 func (c *Client) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
-	msgData := BuildNotificationMessage().
-		noticeBarType(2).
-		noticeTitle(msg.MsgTitle).
-		noticeContent(msg.MsgBody)
-	msgData.ClickTypeInfo.Parameters = map[string]interface{}{
-		"pushParams": msg.MsgAction, //跟客户端协商字段pathParams
+	return c.PushTargetMsg(msg, common.NewTokenTarget(tokens))
+}
+
+//按目标类型推送，支持pushId和别名
+func (c *Client) PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	if target.Type != common.TARGET_TYPE_TOKEN && target.Type != common.TARGET_TYPE_ALIAS {
+		return nil, common.NotSupportedTargetError(c.cfg.Name, target)
 	}
-	if c.cfg.Redirect != "" {
-		msgData.Extra = map[string]interface{}{}
-		msgData.Extra["callback"] = c.cfg.Redirect + fmt.Sprintf("?deviceVendor=%s", c.Name())
-		params := common.CallbackParam{MsgId: msg.Id, Package: c.cfg.Package}
-		paramsData, err := json.Marshal(params)
-		if err != nil {
-			return nil, err
-		}
-		msgData.Extra["callback.param"] = string(paramsData)
-		msgData.Extra["callback.type"] = 3
+	tokens := target.Values
+	msgData, err := c.formatMsg(msg)
+	if err != nil {
+		return nil, err
 	}
 
 	pushid := strings.Join(tokens, ",")
-	res := c.PushNotificationMessageByPushId(c.cfg.AppId, pushid, msgData.toJson(), c.cfg.AppSecret)
+	var res PushResponse
+	if target.Type == common.TARGET_TYPE_ALIAS {
+		res = c.PushNotificationMessageByAlias(c.cfg.AppId, pushid, msgData.toJson(), c.cfg.AppSecret)
+	} else {
+		res = c.PushNotificationMessageByPushId(c.cfg.AppId, pushid, msgData.toJson(), c.cfg.AppSecret)
+	}
 	log.WithFields(log.Fields{"appid": c.cfg.AppId, "sercret": c.cfg.AppSecret, "res": res, "push msg": msgData, "pushid": pushid}).Tracef("%s send msg", c.cfg.Name)
 	if failsInfoMap == nil {
 		failsInfoMap = make(map[string]*common.CallbackResponseItem, len(tokens))
@@ -84,28 +84,80 @@ func (c *Client) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[str
 	return failsInfoMap, nil
 }
 
+//全量推送（任务推送到应用全部用户），返回任务id，可用于统计查询
+func (c *Client) BroadcastAll(msg *common.Msg) (string, error) {
+	msgData, err := c.formatMsg(msg)
+	if err != nil {
+		return "", err
+	}
+	res := c.PushNotificationMessageToApp(c.cfg.AppId, msgData.toJson(), c.cfg.AppSecret)
+	log.WithFields(log.Fields{"appid": c.cfg.AppId, "res": res, "push msg": msgData}).Tracef("%s broadcast all msg", c.cfg.Name)
+	if res.Code != 200 {
+		return "", fmt.Errorf("%s broadcast all msg error:[%v]", c.cfg.Name, res.Message)
+	}
+	taskId := formatTaskId(res.Value)
+	if taskId == "" {
+		return "", fmt.Errorf("%s broadcast all msg empty task id", c.cfg.Name)
+	}
+	return taskId, nil
+}
+
+func (c *Client) formatMsg(msg *common.Msg) (NotificationMessage, error) {
+	msgData := BuildNotificationMessage().
+		noticeBarType(2).
+		noticeTitle(msg.MsgTitle).
+		noticeContent(msg.MsgBody)
+	msgData.ClickTypeInfo.Parameters = map[string]interface{}{
+		"pushParams": msg.MsgAction, //跟客户端协商字段pathParams
+	}
+	if c.cfg.Redirect != "" {
+		msgData.Extra = map[string]interface{}{}
+		msgData.Extra["callback"] = common.CallbackUrl(c.cfg.Redirect, c.Name(), c.cfg.Package, c.cfg.CallbackToken)
+		paramsData, dropped, err := common.NewCallbackParam(msg, c.cfg.Package).Encode(c.cfg.CallbackSecret, c.cfg.CallbackExpire, MAX_CALLBACK_PARAM_LENGTH)
+		if err != nil {
+			log.WithError(err).WithField("msgId", msg.Id).Warnf("%s callback param too long, send without it", c.cfg.Name)
+		} else if len(dropped) > 0 {
+			log.WithField("msgId", msg.Id).WithField("dropped", dropped).Warnf("%s callback extra dropped by length limit", c.cfg.Name)
+		}
+		if paramsData != "" {
+			msgData.Extra["callback.param"] = paramsData
+		}
+		msgData.Extra["callback.type"] = 3
+	}
+	return msgData, nil
+}
+
 type CallBackItem struct {
 	Param   string   `json:"param"`
 	Status  int64    `json:"type"`
 	Targets []string `json:"targets"`
 }
 
+//校验回执地址中的token
+func (c *Client) VerifyCallback(r *http.Request) error {
+	return common.VerifyCallbackToken(r, c.cfg.Name, c.cfg.CallbackToken)
+}
+
 func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
 	res := map[string]interface{}{"errno": 0, "errmsg": "success"}
+	if err := r.ParseForm(); err != nil {
+		log.WithError(err).WithField("deviceVendor", c.cfg.Name).Errorf("invalid callback form")
+		return nil, nil, err
+	}
 	form := r.Form
 	log.WithFields(log.Fields{
 		"deviceVendor": c.cfg.Name,
-		"url":          r.URL.RequestURI(),
-		"header":       r.Header,
-		"form":         form,
+		"url":          common.RedactUri(r),
+		"header":       common.RedactHeader(r),
+		"form":         common.RedactForm(form),
 	}).Tracef("callback")
 	data := ""
 	if data = form.Get("cb"); data == "" {
 		log.WithFields(log.Fields{
 			"deviceVendor": c.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
-			"form":         form,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
+			"form":         common.RedactForm(form),
 		}).Errorf("empty params data")
 		return nil, nil, fmt.Errorf("empty params data")
 	}
@@ -114,17 +166,16 @@ func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[str
 	if err != nil {
 		log.WithFields(log.Fields{
 			"deviceVendor": c.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
-			"form":         form,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
+			"form":         common.RedactForm(form),
 		}).Errorf("empty params data")
 		return nil, nil, err
 	}
 	var callbackResponse common.CallbackResponse
 	for requestId, val := range reciver {
 		item := &common.CallbackResponseItem{}
-		params := common.CallbackParam{}
-		err := json.Unmarshal([]byte(val.Param), &params)
+		params, err := common.DecodeCallbackParam(c.cfg.Name, val.Param, c.cfg.CallbackSecret)
 		if err != nil {
 			log.WithError(err).WithField("deviceVendor", c.cfg.Name).Errorf("invalid callback param: %v", val.Param)
 			continue
@@ -134,19 +185,33 @@ func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[str
 		item.RequestId = requestId
 		item.Timestamp = time.Now().UnixNano() / 1000
 		item.PackageName = params.Package
+		if item.PackageName == "" {
+			item.PackageName = c.cfg.Package
+		}
+		item.Extra = params.Extra
 		status := val.Status
+		eventType := common.EVENT_TYPE_FAILED
 		switch val.Status {
-		case 1:
+		case 1: //送达
 			status = common.CALLBACK_STATUS_OK
-		case 2:
+			eventType = common.EVENT_TYPE_DELIVERED
+		case 2: //点击
 			status = common.CALLBACK_STATUS_OK
-		case 3:
+			eventType = common.EVENT_TYPE_CLICKED
+		case 3: //送达与点击，分别生成送达和点击事件
 			status = common.CALLBACK_STATUS_OK
+			eventType = common.EVENT_TYPE_DELIVERED
 		default:
 			status = common.CALLBACK_STATUS_NEED_RETRY
 		}
 		item.Status = status
+		item.EventType = eventType
 		callbackResponse.Data = append(callbackResponse.Data, item)
+		if val.Status == 3 {
+			clicked := *item
+			clicked.EventType = common.EVENT_TYPE_CLICKED
+			callbackResponse.Data = append(callbackResponse.Data, &clicked)
+		}
 	}
 	return &callbackResponse, res, nil
 }
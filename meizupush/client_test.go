@@ -0,0 +1,23 @@
+package meizupush
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+func TestPushReciverDeliveredAndClicked(t *testing.T) {
+	c := &Client{cfg: &config.PushServerCfg{Name: "meizu"}}
+	r := httptest.NewRequest("POST", "/callback", strings.NewReader(`cb={"id":{"type":3,"targets":["a"]}}`))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, _, err := c.PushReciver(r)
+	if err != nil || len(response.Data) != 2 {
+		t.Fatalf("unexpected callback response %v %v", response, err)
+	}
+	if response.Data[0].EventType != common.EVENT_TYPE_DELIVERED || response.Data[1].EventType != common.EVENT_TYPE_CLICKED {
+		t.Errorf("unexpected event types %s %s", response.Data[0].EventType, response.Data[1].EventType)
+	}
+}
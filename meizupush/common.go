@@ -17,6 +17,8 @@ const (
 	STATUS_CODE = 200
 	//服务端SDK调用API的应用的私钥Secret Key为 appSecret
 	PUSH_API_SERVER = "https://api-push.meizu.com"
+	//回执参数callback.param最大长度
+	MAX_CALLBACK_PARAM_LENGTH = 64
 )
 
 type PushResponse struct {
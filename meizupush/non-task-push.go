@@ -43,11 +43,24 @@ func (c *Client) PushNotificationMessageByPushId(appId string, pushIds string, m
 		"pushIds":     pushIds,
 		"messageJson": messageJson,
 	}
+	return c.pushNotificationMessage(pushNotificationMessageByPushId, pushNotificationMessageMap, appKey)
+}
+
+//别名推送接口（通知栏消息）多个别名以英文逗号分隔
+func (c *Client) PushNotificationMessageByAlias(appId string, alias string, messageJson string, appKey string) PushResponse {
+	pushNotificationMessageMap := map[string]string{
+		"appId":       appId,
+		"alias":       alias,
+		"messageJson": messageJson,
+	}
+	return c.pushNotificationMessage(pushNotificationMessageByAlias, pushNotificationMessageMap, appKey)
+}
 
+func (c *Client) pushNotificationMessage(pushUrl string, pushNotificationMessageMap map[string]string, appKey string) PushResponse {
 	sign := GenerateSign(pushNotificationMessageMap, appKey)
 	pushNotificationMessageMap["sign"] = sign
 
-	result, err := Post(c.client, pushNotificationMessageByPushId, pushNotificationMessageMap)
+	result, err := Post(c.client, pushUrl, pushNotificationMessageMap)
 	response := PushResponse{}
 	if err != nil {
 		response = PushResponse{
@@ -1,8 +1,29 @@
 package meizupush
 
-import "github.com/ddliu/go-httpclient"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"push_sdks/common"
+
+	"github.com/ddliu/go-httpclient"
+)
 
 const getTaskStatistics = PUSH_API_SERVER + "/garcia/api/server/push/statistics/getTaskStatistics"
+const dailyPushStatics = PUSH_API_SERVER + "/garcia/api/server/push/statistics/dailyPushStatics"
+
+//推送统计，targetNo 目标数 validNo 有效数 pushedNo 推送数 acceptNo 送达数 displayNo 展示数 clickNo 点击数
+type Statistics struct {
+	TaskId    string `json:"taskId"`
+	Date      string `json:"date"`
+	TargetNo  int64  `json:"targetNo"`
+	ValidNo   int64  `json:"validNo"`
+	PushedNo  int64  `json:"pushedNo"`
+	AcceptNo  int64  `json:"acceptNo"`
+	DisplayNo int64  `json:"displayNo"`
+	ClickNo   int64  `json:"clickNo"`
+}
 
 //获取任务推送统计
 func GetTaskStatistics(appId string, taskId string, appKey string) PushResponse {
@@ -18,3 +39,78 @@ func GetTaskStatistics(appId string, taskId string, appKey string) PushResponse
 
 	return ResolvePushResponse(res, err)
 }
+
+//获取应用按天推送统计，startTime endTime 格式 yyyyMMdd
+func GetDailyPushStatics(appId string, startTime string, endTime string, appKey string) PushResponse {
+	dailyStaticsMap := map[string]string{
+		"appId":     appId,
+		"startTime": startTime,
+		"endTime":   endTime,
+	}
+
+	sign := GenerateSign(dailyStaticsMap, appKey)
+	dailyStaticsMap["sign"] = sign
+
+	res, err := httpclient.Get(dailyPushStatics, dailyStaticsMap)
+
+	return ResolvePushResponse(res, err)
+}
+
+//按任务id查询推送统计
+func (c *Client) GetTaskStats(taskIds ...string) ([]*common.TaskStats, error) {
+	list := make([]*common.TaskStats, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		res := GetTaskStatistics(c.cfg.AppId, taskId, c.cfg.AppSecret)
+		if res.Code != 200 {
+			return list, fmt.Errorf("%s get task stats error:[%v]", c.cfg.Name, res.Message)
+		}
+		var statistics Statistics
+		if err := decodeValue(res.Value, &statistics); err != nil {
+			return list, fmt.Errorf("%s get task stats error:[%v]", c.cfg.Name, err)
+		}
+		list = append(list, &common.TaskStats{
+			TaskId:       taskId,
+			DeviceVendor: c.cfg.Name,
+			PackageName:  c.cfg.Package,
+			Sent:         statistics.PushedNo,
+			Delivered:    statistics.AcceptNo,
+			Displayed:    statistics.DisplayNo,
+			Clicked:      statistics.ClickNo,
+		})
+	}
+	return list, nil
+}
+
+//按天查询应用推送统计
+func (c *Client) GetDailyStats(start, end time.Time) ([]*common.DailyStats, error) {
+	res := GetDailyPushStatics(c.cfg.AppId, start.Format(common.STATS_DATE_FORMAT), end.Format(common.STATS_DATE_FORMAT), c.cfg.AppSecret)
+	if res.Code != 200 {
+		return nil, fmt.Errorf("%s get daily stats error:[%v]", c.cfg.Name, res.Message)
+	}
+	var statisticsList []Statistics
+	if err := decodeValue(res.Value, &statisticsList); err != nil {
+		return nil, fmt.Errorf("%s get daily stats error:[%v]", c.cfg.Name, err)
+	}
+	list := make([]*common.DailyStats, 0, len(statisticsList))
+	for _, statistics := range statisticsList {
+		list = append(list, &common.DailyStats{
+			Date:         statistics.Date,
+			DeviceVendor: c.cfg.Name,
+			PackageName:  c.cfg.Package,
+			Sent:         statistics.PushedNo,
+			Delivered:    statistics.AcceptNo,
+			Displayed:    statistics.DisplayNo,
+			Clicked:      statistics.ClickNo,
+		})
+	}
+	return list, nil
+}
+
+//PushResponse.Value 为任意json，转换为具体结构
+func decodeValue(value interface{}, v interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -1 +1,39 @@
 package meizupush
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	pushToApp = PUSH_API_SERVER + "/garcia/api/server/push/pushTask/pushToApp"
+)
+
+const (
+	PUSH_TYPE_NOTIFICATION = "0" //通知栏消息
+	PUSH_TYPE_THROUGH      = "1" //透传消息
+)
+
+//全部用户推送（通知栏消息） 返回的value为任务id
+func (c *Client) PushNotificationMessageToApp(appId string, messageJson string, appKey string) PushResponse {
+	pushToAppMap := map[string]string{
+		"appId":       appId,
+		"pushType":    PUSH_TYPE_NOTIFICATION,
+		"messageJson": messageJson,
+	}
+	return c.pushNotificationMessage(pushToApp, pushToAppMap, appKey)
+}
+
+//任务id以数字返回，避免float64科学计数法格式
+func formatTaskId(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
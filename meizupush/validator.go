@@ -0,0 +1,17 @@
+package meizupush
+
+import (
+	"push_sdks/common"
+)
+
+//魅族消息限制：标题32字符，内容100字符
+var MsgRules = common.RuleSet{
+	common.RequiredRule("title", common.MsgTitleField),
+	common.MaxLengthRule("title", 32, common.MsgTitleField),
+	common.RequiredRule("content", common.MsgBodyField),
+	common.MaxLengthRule("content", 100, common.MsgBodyField),
+}
+
+func (c *Client) ValidateMsg(msg *common.Msg) []*common.Violation {
+	return MsgRules.Validate(c.cfg.Name, msg)
+}
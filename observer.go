@@ -0,0 +1,41 @@
+package push_sdks
+
+import (
+	"sync"
+
+	"push_sdks/common"
+)
+
+//推送结果和回执的观察者，如 funnel.Aggregator
+//err不为空时，结果中没有的目标应视为推送失败
+type PushObserver interface {
+	OnSendResults(vendor string, msg *common.Msg, targets []string, results map[string]*common.CallbackResponseItem, err error)
+	OnCallback(vendor string, response *common.CallbackResponse)
+}
+
+var pushObservers []PushObserver
+var pushObserversLock sync.RWMutex
+
+func AddPushObserver(observer PushObserver) {
+	pushObserversLock.Lock()
+	defer pushObserversLock.Unlock()
+	pushObservers = append(pushObservers, observer)
+}
+
+func getPushObservers() []PushObserver {
+	pushObserversLock.RLock()
+	defer pushObserversLock.RUnlock()
+	return pushObservers
+}
+
+func notifySendResults(vendor string, msg *common.Msg, targets []string, results map[string]*common.CallbackResponseItem, err error) {
+	for _, observer := range getPushObservers() {
+		observer.OnSendResults(vendor, msg, targets, results, err)
+	}
+}
+
+func notifyCallback(vendor string, response *common.CallbackResponse) {
+	for _, observer := range getPushObservers() {
+		observer.OnCallback(vendor, response)
+	}
+}
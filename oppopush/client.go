@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"context"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -22,6 +23,9 @@ import (
 const MaxTotalPerBatch = 1000
 const PICTURE_TTL = 30 * 86400
 
+//素材id缓存时长，比图片有效期提前一天过期
+const PICTURE_CACHE_TTL = (PICTURE_TTL - 86400) * time.Second
+
 type OppoPush struct {
 	cfg           *config.PushServerCfg
 	AccessToken   string
@@ -52,27 +56,76 @@ func (c *OppoPush) GetToken() (string, int, error) {
 }
 
 func (c *OppoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	return c.PushTargetMsg(msg, common.NewTokenTarget(tokens))
+}
+
+//按目标类型推送，支持registration_id、别名和全部用户
+func (c *OppoPush) PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	targetType := 0
+	switch target.Type {
+	case common.TARGET_TYPE_TOKEN:
+		targetType = TargetTypeRegID
+	case common.TARGET_TYPE_ALIAS:
+		targetType = TargetTypeAlias
+	case common.TARGET_TYPE_ALL:
+		targetType = TargetTypeAll
+	default:
+		return nil, common.NotSupportedTargetError(c.cfg.Name, target)
+	}
+	res, err := c.pushTarget(msg, targetType, target.Values)
+	keys := target.Keys()
+	if res != nil {
+		if failsInfoMap == nil {
+			failsInfoMap = make(map[string]*common.CallbackResponseItem, len(keys))
+		}
+		for _, token := range keys {
+			failsInfoMap[token] = &common.CallbackResponseItem{
+				Status:       formatStatus(res.Code),
+				Description:  res.Message,
+				RequestId:    res.Data.MessageID,
+				Token:        token,
+				DeviceVendor: c.cfg.Name,
+				PackageName:  c.cfg.Package,
+			}
+		}
+	}
+	if err != nil {
+		return failsInfoMap, err
+	}
+	log.WithField("result", res).Debugf("%s broadcast msg success", c.cfg.Name)
+	return failsInfoMap, nil
+}
+
+//全量推送，返回推送任务id，可用于统计查询
+func (c *OppoPush) BroadcastAll(msg *common.Msg) (string, error) {
+	res, err := c.pushTarget(msg, TargetTypeAll, nil)
+	if err != nil {
+		return "", err
+	}
+	log.WithField("result", res).Debugf("%s broadcast all msg success", c.cfg.Name)
+	return res.Data.TaskId, nil
+}
+
+//保存通知栏消息内容体后广播推送
+func (c *OppoPush) pushTarget(msg *common.Msg, targetType int, targets []string) (*BroadcastSendResult, error) {
 	//保存通知栏消息内容体
 	msg0 := NewSaveMessageContent(msg.MsgTitle, msg.MsgBody).
 		SetSubTitle(msg.SubMsgTile)
 	msg0.ActionParameters = fmt.Sprintf("{\"appData\": \"%s\"}", msg.MsgAction)
 	msg0.AppMessageID = fmt.Sprintf("%v_%v", msg.Id, time.Now().UnixNano())
-	if len(msg0.Title) > 50 {
-		msg0.Title = msg0.Title[:50]
-	}
-	if len(msg0.SubTitle) > 10 {
-		msg0.SubTitle = msg0.SubTitle[:10]
-	}
+	msg0.Title = truncate(msg0.Title, 50)
+	msg0.SubTitle = truncate(msg0.SubTitle, 10)
 	//your channel id
 	msg0.ChannelID = msg.ChannelID
 
-	msg0.CallBackURL = c.cfg.Redirect + fmt.Sprintf("?deviceVendor=%s", c.Name())
-	params := common.CallbackParam{MsgId: msg.Id, Package: c.cfg.Package}
-	paramsData, err := json.Marshal(params)
+	msg0.CallBackURL = common.CallbackUrl(c.cfg.Redirect, c.Name(), c.cfg.Package, c.cfg.CallbackToken)
+	paramsData, dropped, err := common.NewCallbackParam(msg, c.cfg.Package).Encode(c.cfg.CallbackSecret, c.cfg.CallbackExpire, MaxCallbackParamLength)
 	if err != nil {
-		return nil, err
+		log.WithError(err).WithField("msgId", msg.Id).Warnf("%s callback param too long, send without it", c.cfg.Name)
+	} else if len(dropped) > 0 {
+		log.WithField("msgId", msg.Id).WithField("dropped", dropped).Warnf("%s callback extra dropped by length limit", c.cfg.Name)
 	}
-	msg0.CallBackParameter = string(paramsData)
+	msg0.CallBackParameter = paramsData
 	if msg.ImgUrl != "" {
 		picId, err := c.GetImgId(msg.ImgUrl)
 		if err != nil {
@@ -81,9 +134,7 @@ func (c *OppoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[s
 		if picId != "" {
 			msg0.Style = 3
 			msg0.BigPictureId = picId
-			if len(msg0.Content) > 50 {
-				msg0.Content = msg0.Content[:50]
-			}
+			msg0.Content = truncate(msg0.Content, 50)
 		}
 	}
 
@@ -98,29 +149,13 @@ func (c *OppoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[s
 	}
 	//广播推送-通知栏消息
 	broadcast := NewBroadcast(result.Data.MessageID).
-		SetTargetType(2).
-		SetTargetValue(strings.Join(tokens, ";"))
+		SetTargetType(targetType).
+		SetTargetValue(strings.Join(targets, ";"))
 	res, err := c.broadcast(broadcast)
-	if res != nil {
-		if failsInfoMap == nil {
-			failsInfoMap = make(map[string]*common.CallbackResponseItem, len(tokens))
-		}
-		for _, token := range tokens {
-			failsInfoMap[token] = &common.CallbackResponseItem{
-				Status:       formatStatus(res.Code),
-				Description:  res.Message,
-				RequestId:    res.Data.MessageID,
-				Token:        token,
-				DeviceVendor: c.cfg.Name,
-				PackageName:  c.cfg.Package,
-			}
-		}
-	}
 	if err != nil {
-		return failsInfoMap, fmt.Errorf("%s broadcast msg error:[%v]", c.cfg.Name, err)
+		return res, fmt.Errorf("%s broadcast msg error:[%v]", c.cfg.Name, err)
 	}
-	log.WithField("result", res).Debugf("%s broadcast msg success", c.cfg.Name)
-	return nil, nil
+	return res, nil
 }
 
 type CallBackItem struct {
@@ -132,21 +167,45 @@ type CallBackItem struct {
 	EventType string `json:"eventType"`
 }
 
+//回执请求头
+const (
+	CALLBACK_APP_KEY_HEADER   = "appKey"
+	CALLBACK_TIMESTAMP_HEADER = "timestamp"
+	CALLBACK_SIGN_HEADER      = "sign"
+)
+
+//开启callback_sign时校验请求头中的appKey和签名，再校验回执地址中的token
+func (c *OppoPush) VerifyCallback(r *http.Request) error {
+	if c.cfg.CallbackSign {
+		if appKey := r.Header.Get(CALLBACK_APP_KEY_HEADER); appKey != c.cfg.AppKey {
+			return &common.CallbackAuthError{DeviceVendor: c.cfg.Name, Reason: fmt.Sprintf("invalid appKey header [%s]", appKey)}
+		}
+		timestamp := r.Header.Get(CALLBACK_TIMESTAMP_HEADER)
+		if err := common.CheckCallbackTimestamp(c.cfg.Name, timestamp); err != nil {
+			return err
+		}
+		if err := common.VerifyCallbackSign(c.cfg.Name, r.Header.Get(CALLBACK_SIGN_HEADER), authSign(c.cfg.AppKey, timestamp, c.cfg.AppSecret)); err != nil {
+			return err
+		}
+	}
+	return common.VerifyCallbackToken(r, c.cfg.Name, c.cfg.CallbackToken)
+}
+
 func (c *OppoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
 	res := map[string]interface{}{"errno": 0, "errmsg": "success"}
 	requestBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields{
 			"deviceVendor": c.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
 		}).Errorf("invalid request body")
 		return nil, nil, err
 	}
 	log.WithFields(log.Fields{
 		"deviceVendor": c.cfg.Name,
-		"url":          r.URL.RequestURI(),
-		"header":       r.Header,
+		"url":          common.RedactUri(r),
+		"header":       common.RedactHeader(r),
 	}).Tracef("callback")
 
 	var reciver []CallBackItem
@@ -154,8 +213,8 @@ func (c *OppoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[s
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields{
 			"deviceVendor": c.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
 			"form":         string(requestBody),
 		}).Errorf("empty params data")
 		return nil, nil, err
@@ -163,8 +222,7 @@ func (c *OppoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[s
 	var callbackResponse common.CallbackResponse
 	for _, val := range reciver {
 		item := &common.CallbackResponseItem{}
-		params := common.CallbackParam{}
-		err := json.Unmarshal([]byte(val.Param), &params)
+		params, err := common.DecodeCallbackParam(c.cfg.Name, val.Param, c.cfg.CallbackSecret)
 		if err != nil {
 			log.WithError(err).WithField("deviceVendor", c.cfg.Name).Errorf("invalid callback param: %v", val.Param)
 			continue
@@ -174,32 +232,64 @@ func (c *OppoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[s
 		item.RequestId = val.MessageId
 		item.Timestamp = time.Now().UnixNano() / 1000
 		item.PackageName = params.Package
-		item.Status = common.CALLBACK_STATUS_OK
-		if val.EventType != "push_arrive" {
+		if item.PackageName == "" {
+			item.PackageName = c.cfg.Package
+		}
+		item.Extra = params.Extra
+		switch val.EventType {
+		case "push_arrive": //送达
+			item.Status = common.CALLBACK_STATUS_OK
+			item.EventType = common.EVENT_TYPE_DELIVERED
+		case "regid_invalid": //registration_id失效
+			item.Status = common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN
+			item.EventType = common.EVENT_TYPE_FAILED
+		default:
 			item.Status = common.UNKONW
+			item.EventType = common.EVENT_TYPE_FAILED
 		}
 		callbackResponse.Data = append(callbackResponse.Data, item)
 	}
 	return &callbackResponse, res, nil
 }
 
+//拉取失效的registration_id列表
+func (c *OppoPush) FetchInvalidTokens() ([]*common.InvalidTokenEvent, error) {
+	tokenInstance, err := GetToken(c.cfg.AppKey, c.cfg.AppSecret)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.fetchInvalidRegidList(tokenInstance.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s fetch invalid regIds error:[%v]", c.cfg.Name, err)
+	}
+	now := time.Now().Unix()
+	events := make([]*common.InvalidTokenEvent, 0, len(res.Data.RegistrationIds))
+	for _, regId := range res.Data.RegistrationIds {
+		events = append(events, &common.InvalidTokenEvent{
+			DeviceVendor: c.cfg.Name,
+			PackageName:  c.cfg.Package,
+			Token:        regId,
+			Status:       common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN,
+			Reason:       "feedback invalid regId",
+			Timestamp:    now,
+		})
+	}
+	return events, nil
+}
+
 func (c *OppoPush) RegisterDeviceToken(deviceId string) (deviceToken string, err error) {
 	return deviceId, nil
 }
 
 func (c *OppoPush) GetImgId(imgUrl string) (string, error) {
-	fileName := common.GetImgNameFromUrl(imgUrl)
-	imgUrl += "?x-oss-process=image/quality,q_100/resize,limit_0,m_fill,w_876,h_324"
-	path := os.TempDir()
-	filePath, err := common.DowlodPic(imgUrl, path, c.cfg.Name, fileName)
+	imgId, err := common.UploadImage(context.Background(), imgUrl, c.cfg.Name, c.cfg.Package, common.OppoBigPictureSpec, PICTURE_CACHE_TTL, c.UploadPic)
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields{
 			"url": imgUrl,
-		}).Error("download img err ", c.cfg.Name)
+		}).Error("upload img err ", c.cfg.Name)
 		return "", err
 	}
-	imgId, err := c.UploadPic(filePath)
-	return imgId, err
+	return imgId, nil
 }
 
 //图片要求尺寸876*324 px,文件大小1M以内，格式为PNG/JPG/JPEG
@@ -236,10 +326,15 @@ func (c *OppoPush) UploadPic(imgPath string) (string, error) {
 	return result.Data.BigPictureId, nil
 }
 
-//图片要求尺寸144*144 px，文件大小为50k以内,格式为PNG/JPG/JPEG
+//图片要求尺寸144*144 px，文件大小为50k以内,格式为PNG/JPG/JPEG，上传前按图标规格处理
 func (c *OppoPush) UploadIcon(iconPath string) (string, error) {
+	processedPath, err := common.ProcessImageFile(iconPath, common.OppoIconSpec)
+	if err != nil {
+		return "", fmt.Errorf("%s process icon error:[%v]", c.cfg.Name, err)
+	}
+	defer os.Remove(processedPath)
 	params := map[string]string{"auth_token": tokenInstance.AccessToken, "picture_ttl": strconv.Itoa(PICTURE_TTL)}
-	res, err := doUpload(MediaHost+UploadSmallPicURL, iconPath, "icon"+filepath.Ext(iconPath), params)
+	res, err := doUpload(MediaHost+UploadSmallPicURL, processedPath, "icon"+filepath.Ext(processedPath), params)
 	if err != nil {
 		return "", err
 	}
@@ -380,6 +475,108 @@ func (c *OppoPush) fetchInvalidRegidList(accesstoken string) (*FetchInvalidRegid
 	return &result, nil
 }
 
+//撤回消息，RequestId为推送返回的message_id
+func (c *OppoPush) Recall(ctx context.Context, requests ...*common.RecallRequest) ([]*common.RecallResult, error) {
+	tokenInstance, err := GetToken(c.cfg.AppKey, c.cfg.AppSecret)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*common.RecallResult, 0, len(requests))
+	for _, request := range requests {
+		if err := ctx.Err(); err != nil {
+			return list, err
+		}
+		item := &common.RecallResult{DeviceVendor: c.cfg.Name, PackageName: c.cfg.Package, RequestId: request.RequestId}
+		params := url.Values{}
+		params.Add("auth_token", tokenInstance.AccessToken)
+		params.Add("message_id", request.RequestId)
+		bytes, err := doPost(PushHost+RevokeMessageURL, params)
+		if err != nil {
+			item.Description = err.Error()
+			list = append(list, item)
+			continue
+		}
+		var result RevokeResult
+		if err = json.Unmarshal(bytes, &result); err != nil {
+			item.Description = err.Error()
+		} else {
+			item.Success = result.Code == 0
+			item.Description = result.Message
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+//按任务id查询推送统计，taskIds为广播推送返回的task_id
+func (c *OppoPush) GetTaskStats(taskIds ...string) ([]*common.TaskStats, error) {
+	params := url.Values{}
+	params.Add("task_ids", strings.Join(taskIds, ","))
+	res, err := c.statistics(StatisticsTaskURL, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s get task stats error:[%v]", c.cfg.Name, err)
+	}
+	list := make([]*common.TaskStats, 0, len(res.Data))
+	for _, data := range res.Data {
+		list = append(list, &common.TaskStats{
+			TaskId:       data.TaskId,
+			DeviceVendor: c.cfg.Name,
+			PackageName:  c.cfg.Package,
+			Sent:         data.PushCount,
+			Delivered:    data.ArriveCount,
+			Displayed:    data.ShowCount,
+			Clicked:      data.ClickCount,
+		})
+	}
+	return list, nil
+}
+
+//按天查询应用推送统计
+func (c *OppoPush) GetDailyStats(start, end time.Time) ([]*common.DailyStats, error) {
+	params := url.Values{}
+	params.Add("start_date", start.Format(common.STATS_DATE_FORMAT))
+	params.Add("end_date", end.Format(common.STATS_DATE_FORMAT))
+	res, err := c.statistics(StatisticsAppDailyURL, params)
+	if err != nil {
+		return nil, fmt.Errorf("%s get daily stats error:[%v]", c.cfg.Name, err)
+	}
+	list := make([]*common.DailyStats, 0, len(res.Data))
+	for _, data := range res.Data {
+		list = append(list, &common.DailyStats{
+			Date:         data.Date,
+			DeviceVendor: c.cfg.Name,
+			PackageName:  c.cfg.Package,
+			Sent:         data.PushCount,
+			Delivered:    data.ArriveCount,
+			Displayed:    data.ShowCount,
+			Clicked:      data.ClickCount,
+		})
+	}
+	return list, nil
+}
+
+// 数据查询
+func (c *OppoPush) statistics(path string, params url.Values) (*StatisticsResult, error) {
+	tokenInstance, err := GetToken(c.cfg.AppKey, c.cfg.AppSecret)
+	if err != nil {
+		return nil, err
+	}
+	params.Add("auth_token", tokenInstance.AccessToken)
+	bytes, err := doGet(DataHost+path, "?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var result StatisticsResult
+	err = json.Unmarshal(bytes, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return nil, errors.New(result.Message)
+	}
+	return &result, nil
+}
+
 func defaultForm(msg *NotificationMessage) url.Values {
 	form := url.Values{}
 	if msg.AppMessageID != "" {
@@ -0,0 +1,41 @@
+package oppopush
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+func TestVerifyCallback(t *testing.T) {
+	c := &OppoPush{cfg: &config.PushServerCfg{Name: "oppo", AppKey: "key", AppSecret: "secret", CallbackSign: true}}
+	timestamp := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+	r := httptest.NewRequest("POST", "/callback", nil)
+	r.Header.Set(CALLBACK_APP_KEY_HEADER, "key")
+	r.Header.Set(CALLBACK_TIMESTAMP_HEADER, timestamp)
+	r.Header.Set(CALLBACK_SIGN_HEADER, authSign("key", timestamp, "secret"))
+	if err := c.VerifyCallback(r); err != nil {
+		t.Errorf("valid callback headers rejected: %v", err)
+	}
+	r.Header.Set(CALLBACK_APP_KEY_HEADER, "other")
+	if err := c.VerifyCallback(r); !errors.Is(err, common.ErrCallbackUnauthorized) {
+		t.Errorf("callback from other app accepted: %v", err)
+	}
+}
+
+func TestValidateMsgBigPictureContent(t *testing.T) {
+	c := &OppoPush{cfg: &config.PushServerCfg{Name: "oppo"}}
+	msg := &common.Msg{MsgTitle: "title", MsgBody: strings.Repeat("字", 60), ImgUrl: "https://example.com/a.png"}
+	violations := c.ValidateMsg(msg)
+	if len(violations) != 1 || !violations[0].Warning {
+		t.Errorf("big picture content should only be a warning %v", violations)
+	}
+	if err := common.NewValidationError(violations); err != nil {
+		t.Errorf("big picture content should not block sending: %v", err)
+	}
+}
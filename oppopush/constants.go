@@ -5,6 +5,7 @@ const (
 	PushHost     = "https://api.push.oppomobile.com"
 	FeedbackHost = "https://feedback.push.oppomobile.com"
 	MediaHost    = "https://api-media.push.heytapmobi.com"
+	DataHost     = "https://data-api.push.oppomobile.com"
 )
 
 const (
@@ -16,4 +17,18 @@ const (
 	FetchInvalidRegidListURL = "/server/v1/feedback/fetch_invalid_regidList"          // Feedback-获取失效的 registration_id 列表
 	UploadSmallPicURL        = "/server/v1/media/upload/small_picture"                //上传图标 图片要求尺寸144*144 px，文件大小为50k以内,格式为PNG/JPG/JPEG
 	UploadBigPicURL          = "/server/v1/media/upload/big_picture"                  //图片要求尺寸876*324 px,文件大小1M以内，格式为PNG/JPG/JPEG
+	RevokeMessageURL         = "/server/v1/message/notification/revoke"               // 撤回通知栏消息
+	StatisticsTaskURL        = "/server/v1/statistics/task"                           // 数据查询-推送任务统计
+	StatisticsAppDailyURL    = "/server/v1/statistics/app/daily"                      // 数据查询-应用按天统计
+)
+
+// 广播推送目标类型
+const (
+	TargetTypeAll   = 1 // 全部用户
+	TargetTypeRegID = 2 // registration_id
+	TargetTypeAlias = 5 // 别名 alias_name
+)
+
+const (
+	MaxCallbackParamLength = 100 // call_back_parameter 最大长度
 )
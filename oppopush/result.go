@@ -62,3 +62,24 @@ type FetchInvalidRegidListSendResult struct {
 		TotalCount      int      `json:"totalCount"`
 	} `json:"data,omitempty"` // 可选，返回结果
 }
+
+type RevokeResult struct {
+	Code    int    `json:"code"`              // 必选,返回码
+	Message string `json:"message,omitempty"` // 可选，返回消息
+}
+
+type StatisticsResult struct {
+	Code    int              `json:"code"`              // 必选,返回码
+	Message string           `json:"message,omitempty"` // 可选，返回消息
+	Data    []StatisticsData `json:"data,omitempty"`    // 可选，返回结果
+}
+
+type StatisticsData struct {
+	TaskId      string `json:"task_id,omitempty"`
+	Date        string `json:"date,omitempty"`
+	TargetCount int64  `json:"target_count"` // 目标数
+	PushCount   int64  `json:"push_count"`   // 发送数
+	ArriveCount int64  `json:"arrive_count"` // 到达数
+	ShowCount   int64  `json:"show_count"`   // 展示数
+	ClickCount  int64  `json:"click_count"`  // 点击数
+}
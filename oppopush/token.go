@@ -37,8 +37,7 @@ func GetToken(appKey, masterSecret string) (*OppoToken, error) {
 		return tokenInstance, nil
 	}
 	timestamp := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
-	shaByte := sha256.Sum256([]byte(appKey + timestamp + masterSecret))
-	sign := fmt.Sprintf("%x", shaByte)
+	sign := authSign(appKey, timestamp, masterSecret)
 	params := url.Values{}
 	params.Add("app_key", appKey)
 	params.Add("sign", sign)
@@ -65,3 +64,9 @@ func GetToken(appKey, masterSecret string) (*OppoToken, error) {
 	tokenInstance.CreateTime = result.Data.CreateTime
 	return tokenInstance, nil
 }
+
+//鉴权签名 sha256(appKey+timestamp+masterSecret)，回执签名使用相同规则
+func authSign(appKey, timestamp, masterSecret string) string {
+	shaByte := sha256.Sum256([]byte(appKey + timestamp + masterSecret))
+	return fmt.Sprintf("%x", shaByte)
+}
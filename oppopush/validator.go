@@ -0,0 +1,40 @@
+package oppopush
+
+import (
+	"push_sdks/common"
+	"unicode/utf8"
+)
+
+//OPPO消息限制：标题50字符，子标题10字符，内容200字符，大图样式内容50字符
+//大图样式的内容限制只做提示，图片上传成功时截断内容，失败时按普通通知发送完整内容
+var MsgRules = common.RuleSet{
+	common.RequiredRule("title", common.MsgTitleField),
+	common.MaxLengthRule("title", 50, common.MsgTitleField),
+	common.MaxLengthRule("sub_title", 10, common.MsgSubTitleField),
+	common.RequiredRule("content", common.MsgBodyField),
+	common.MaxLengthRule("content", 200, common.MsgBodyField),
+	common.WarningRule(bigPictureContentRule),
+	common.UrlRule("img_url", common.MsgImgUrlField, "http", "https"),
+}
+
+func bigPictureContentRule(msg *common.Msg) *common.Violation {
+	if msg.ImgUrl == "" {
+		return nil
+	}
+	if length := utf8.RuneCountInString(msg.MsgBody); length > 50 {
+		return &common.Violation{Field: "content", Rule: common.RULE_MAX_LENGTH, Message: "big picture style content length exceeds 50, will be truncated"}
+	}
+	return nil
+}
+
+func (c *OppoPush) ValidateMsg(msg *common.Msg) []*common.Violation {
+	return MsgRules.Validate(c.cfg.Name, msg)
+}
+
+//按字符截断，OPPO长度限制中英文均按一个字符计算，直接调用PushMsg未经校验时使用
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,77 @@
+package push_sdks
+
+import (
+	"context"
+	"fmt"
+
+	"push_sdks/common"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//支持撤回消息的厂商实现（华为、vivo、OPPO，小米只能取消未下发的定时消息）
+type RecallSdkApi interface {
+	Recall(ctx context.Context, requests ...*common.RecallRequest) ([]*common.RecallResult, error)
+}
+
+//按推送结果撤回消息，按厂商和包名分组调用，同一消息的token合并到一个撤回请求，返回按厂商名分组的撤回结果
+//厂商不支持撤回时对应结果Success为false，单个厂商失败不影响其它厂商，返回最后一个错误
+func RecallMsg(ctx context.Context, items []*common.CallbackResponseItem) (map[string][]*common.RecallResult, error) {
+	type recallKey struct {
+		vendor      string
+		packageName string
+	}
+	groups := map[recallKey][]*common.RecallRequest{}
+	requests := map[string]*common.RecallRequest{}
+	seenTokens := map[string]bool{}
+	for _, item := range items {
+		if item == nil || item.RequestId == "" {
+			continue
+		}
+		key := recallKey{vendor: item.DeviceVendor, packageName: item.PackageName}
+		requestKey := key.vendor + "_" + key.packageName + "_" + item.RequestId
+		request, ok := requests[requestKey]
+		if !ok {
+			request = &common.RecallRequest{RequestId: item.RequestId}
+			requests[requestKey] = request
+			groups[key] = append(groups[key], request)
+		}
+		if item.Token != "" && !seenTokens[requestKey+"_"+item.Token] {
+			seenTokens[requestKey+"_"+item.Token] = true
+			request.Tokens = append(request.Tokens, item.Token)
+		}
+	}
+
+	var lastErr error
+	results := make(map[string][]*common.RecallResult, len(groups))
+	for key, requests := range groups {
+		list, err := recall(ctx, key.vendor, key.packageName, requests)
+		if err != nil {
+			log.WithError(err).WithField("requests", len(requests)).Errorf("recall msg error client name :[%s]", key.vendor)
+			lastErr = err
+		}
+		results[key.vendor] = append(results[key.vendor], list...)
+	}
+	return results, lastErr
+}
+
+func recall(ctx context.Context, vendor, packageName string, requests []*common.RecallRequest) ([]*common.RecallResult, error) {
+	sdk, _, err := findPushSdk(vendor, packageName)
+	if err == nil {
+		recallSdk, ok := sdk.(RecallSdkApi)
+		if ok {
+			return recallSdk.Recall(ctx, requests...)
+		}
+		err = fmt.Errorf("%s recall: %w", vendor, common.ErrNotSupported)
+	}
+	list := make([]*common.RecallResult, 0, len(requests))
+	for _, request := range requests {
+		list = append(list, &common.RecallResult{
+			DeviceVendor: vendor,
+			PackageName:  packageName,
+			RequestId:    request.RequestId,
+			Description:  err.Error(),
+		})
+	}
+	return list, err
+}
@@ -0,0 +1,77 @@
+package push_sdks
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+type recallSdk struct {
+	ctx      context.Context
+	requests []*common.RecallRequest
+}
+
+func (s *recallSdk) NeedAccessToken() bool {
+	return false
+}
+
+func (s *recallSdk) Name() string {
+	return "huawei"
+}
+
+func (s *recallSdk) GetToken() (string, int, error) {
+	return "", 0, nil
+}
+
+func (s *recallSdk) PushMsg(msg *common.Msg, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	return nil, nil
+}
+
+func (s *recallSdk) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
+	return nil, nil, nil
+}
+
+func (s *recallSdk) Recall(ctx context.Context, requests ...*common.RecallRequest) ([]*common.RecallResult, error) {
+	s.ctx = ctx
+	s.requests = requests
+	list := make([]*common.RecallResult, 0, len(requests))
+	for _, request := range requests {
+		list = append(list, &common.RecallResult{DeviceVendor: "huawei", RequestId: request.RequestId, Success: true})
+	}
+	return list, nil
+}
+
+func TestRecallMsg(t *testing.T) {
+	cfg := &config.PushServerCfg{Name: "huawei", Package: "com.example"}
+	sdk := &recallSdk{}
+	pushConfigLock.Lock()
+	pushConfigServers = map[string]*config.PushServerCfg{cfg.GetPushServerKey(): cfg}
+	pushConfigLock.Unlock()
+	pushServers.Store(cfg.GetPushServerKey(), sdk)
+	defer func() {
+		pushServers.Delete(cfg.GetPushServerKey())
+		pushConfigLock.Lock()
+		pushConfigServers = nil
+		pushConfigLock.Unlock()
+	}()
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "recall")
+	results, err := RecallMsg(ctx, []*common.CallbackResponseItem{
+		{DeviceVendor: "huawei", PackageName: "com.example", RequestId: "m1", Token: "a"},
+		{DeviceVendor: "huawei", PackageName: "com.example", RequestId: "m1", Token: "b"},
+		{DeviceVendor: "huawei", PackageName: "com.example", RequestId: "m1", Token: "a"},
+	})
+	if err != nil || len(results["huawei"]) != 1 || !results["huawei"][0].Success {
+		t.Fatalf("unexpected recall results %v %v", results, err)
+	}
+	if sdk.ctx.Value(ctxKey{}) != "recall" {
+		t.Errorf("recall ctx should be passed to the sdk")
+	}
+	if len(sdk.requests) != 1 || sdk.requests[0].RequestId != "m1" || len(sdk.requests[0].Tokens) != 2 {
+		t.Errorf("unexpected recall requests %+v", sdk.requests[0])
+	}
+}
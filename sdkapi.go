@@ -28,24 +28,37 @@ type SdkApi interface {
 	PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error)
 }
 
+//支持别名、账号、topic等非token目标推送的厂商实现
+type TargetSdkApi interface {
+	PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error)
+}
+
+//支持全量推送的厂商实现，返回的任务id可用于统计和撤回
+type BroadcastSdkApi interface {
+	BroadcastAll(msg *common.Msg) (taskId string, err error)
+}
+
 var pushServers = sync.Map{}
 var defaultName string
 var pushConfigServers map[string]*config.PushServerCfg
+var pushConfigLock sync.RWMutex
 
 const (
-	MAX_MSG_TITLE_LENGTH = 40
-	MAX_BATCH_MSG_NUM    = 800
+	MAX_BATCH_MSG_NUM = 800
 )
 
 
 func InitPushServers(cfg []config.PushServerCfg) (err error) {
-	pushConfigServers = make(map[string]*config.PushServerCfg, len(cfg))
+	configServers := make(map[string]*config.PushServerCfg, len(cfg))
 	for _, item := range cfg {
 		val := item
-		pushConfigServers[val.GetPushServerKey()] = &val
+		configServers[val.GetPushServerKey()] = &val
 	}
+	pushConfigLock.Lock()
+	pushConfigServers = configServers
+	pushConfigLock.Unlock()
 
-	for _, val := range pushConfigServers {
+	for _, val := range configServers {
 		err = initSigleServerConfig(val)
 		if err != nil {
 			return err
@@ -72,7 +85,7 @@ func initSigleServerConfig(serverCofig *config.PushServerCfg) error {
 	item := serverCofig
 	var sdk SdkApi
 	switch item.Name {
-	case "huawei":
+	case huawei.VENDOR_HUAWEI, huawei.VENDOR_HUAWEI_WEBPUSH, huawei.VENDOR_HUAWEI_APNS, huawei.VENDOR_HUAWEI_QUICKAPP:
 		sdk, err = huawei.NewClient(*item)
 		if err != nil {
 			return err
@@ -103,6 +116,11 @@ func initSigleServerConfig(serverCofig *config.PushServerCfg) error {
 		if err != nil {
 			return err
 		}
+	case "fcm":
+		sdk, err = googlepush.NewFcmClient(*item)
+		if err != nil {
+			return err
+		}
 	case "meizu":
 		sdk, err = meizupush.NewClient(*item)
 		if err != nil {
@@ -139,6 +157,34 @@ func GetPushSdkByName(packageName, name string) SdkApi {
 	return GetPushSdkByKey(serverKey)
 }
 
+//按厂商和包名精确查找客户端，不回退到默认客户端
+//包名为空时只有该厂商仅配置了一个应用才能找到，配置了多个应用时回执地址必须带包名
+func findPushSdk(deviceVendor, packageName string) (SdkApi, *config.PushServerCfg, error) {
+	pushConfigLock.RLock()
+	defer pushConfigLock.RUnlock()
+	var found *config.PushServerCfg
+	for _, pushConfig := range pushConfigServers {
+		if !strings.EqualFold(pushConfig.Name, deviceVendor) {
+			continue
+		}
+		if packageName != "" && pushConfig.Package != packageName {
+			continue
+		}
+		if found != nil {
+			return nil, nil, fmt.Errorf("push client name :[%s] has multiple packages, package is required", deviceVendor)
+		}
+		found = pushConfig
+	}
+	if found == nil {
+		return nil, nil, fmt.Errorf("push client not found name :[%s] package :[%s]", deviceVendor, packageName)
+	}
+	sdk, ok := pushServers.Load(found.GetPushServerKey())
+	if !ok {
+		return nil, nil, fmt.Errorf("push client not init name :[%s] package :[%s]", deviceVendor, packageName)
+	}
+	return sdk.(SdkApi), found, nil
+}
+
 func GetPushSdkByKey(name string) SdkApi {
 	name = strings.ToLower(name)
 	sdk, ok := pushServers.Load(name)
@@ -154,11 +200,13 @@ func GetPushServers() sync.Map {
 
 func GetPushServerByName(name string) SdkApi {
 	serverKey := defaultName
+	pushConfigLock.RLock()
 	for _, pushConfig := range pushConfigServers {
 		if pushConfig.Name == name {
 			serverKey = pushConfig.GetPushServerKey()
 		}
 	}
+	pushConfigLock.RUnlock()
 	ret, ok := pushServers.Load(serverKey)
 	if ok {
 		return ret.(SdkApi)
@@ -173,7 +221,19 @@ func PushBatchMsg(ctx context.Context, msg *common.Msg, name string, tokens []st
 	}).Debug("begin to push msg")
 	var err error
 	sdk := GetPushSdkByName(msg.PackageName, name)
-	resultList, err := sdk.PushMsg(formatMsg(msg), tokens)
+	if err = validateMsg(sdk, msg); err != nil {
+		log.WithError(err).Errorf("push msg invalid client name :[%s]", name)
+		return nil, err
+	}
+	aliveTokens, skipped := filterSuppressedTokens(sdk.Name(), tokens)
+	if len(aliveTokens) == 0 {
+		log.WithField("name", name).Debug("all tokens are suppressed, skip push")
+		handleSendResults(sdk.Name(), msg, common.NewTokenTarget(tokens), skipped, nil)
+		return skipped, nil
+	}
+	resultList, err := sdk.PushMsg(msg, aliveTokens)
+	resultList = mergeSkippedResults(resultList, skipped)
+	handleSendResults(sdk.Name(), msg, common.NewTokenTarget(tokens), resultList, err)
 	if err != nil {
 		log.WithError(err).Errorf("push msg error client name :[%s]", name)
 		return resultList, err
@@ -194,7 +254,17 @@ func PushMsg(ctx context.Context, msg *common.Msg, name, token string) (*common.
 		"msg":   msg,
 	}).Debug("begin to push msg")
 	sdk := GetPushSdkByName(msg.PackageName, name)
-	resultList, err := sdk.PushMsg(formatMsg(msg), []string{token})
+	if err := validateMsg(sdk, msg); err != nil {
+		log.WithError(err).Errorf("push msg invalid client name :[%s]", name)
+		return nil, err
+	}
+	if _, skipped := filterSuppressedTokens(sdk.Name(), []string{token}); len(skipped) > 0 {
+		log.WithField("name", name).WithField("token", token).Debug("token is suppressed, skip push")
+		handleSendResults(sdk.Name(), msg, common.NewTokenTarget([]string{token}), skipped, nil)
+		return skipped[token], nil
+	}
+	resultList, err := sdk.PushMsg(msg, []string{token})
+	handleSendResults(sdk.Name(), msg, common.NewTokenTarget([]string{token}), resultList, err)
 	log.WithFields(log.Fields{
 		"name":   name,
 		"token":  token,
@@ -208,9 +278,120 @@ func PushMsg(ctx context.Context, msg *common.Msg, name, token string) (*common.
 	return nil, err
 }
 
-func formatMsg(msg *common.Msg) *common.Msg {
-	if len(msg.MsgTitle) > MAX_MSG_TITLE_LENGTH {
-		msg.MsgTitle = msg.MsgTitle[:MAX_MSG_TITLE_LENGTH]
+//按目标类型推送 token走PushMsg，其它类型需厂商支持
+func PushTargetMsg(ctx context.Context, msg *common.Msg, name string, target *common.Target) (map[string]*common.CallbackResponseItem, error) {
+	if target == nil {
+		return nil, fmt.Errorf("empty push target client name :[%s]", name)
+	}
+	if target.Type == common.TARGET_TYPE_TOKEN {
+		return PushBatchMsg(ctx, msg, name, target.Values)
+	}
+	log.WithFields(log.Fields{
+		"name":   name,
+		"target": common.GetTargetTypeName(target.Type),
+		"values": target.Values,
+	}).Debug("begin to push target msg")
+	sdk, _, err := findPushSdk(name, msg.PackageName)
+	if err != nil {
+		log.WithError(err).Errorf("push target msg invalid client name :[%s]", name)
+		return nil, err
+	}
+	targetSdk, ok := sdk.(TargetSdkApi)
+	if !ok {
+		return nil, common.NotSupportedTargetError(sdk.Name(), target)
+	}
+	if err = validateMsg(sdk, msg); err != nil {
+		log.WithError(err).Errorf("push target msg invalid client name :[%s]", name)
+		return nil, err
+	}
+	resultList, err := targetSdk.PushTargetMsg(msg, target)
+	handleSendResults(sdk.Name(), msg, target, resultList, err)
+	if err != nil {
+		log.WithError(err).Errorf("push target msg error client name :[%s]", name)
+		return resultList, err
+	}
+	log.WithFields(log.Fields{
+		"name":       name,
+		"target":     common.GetTargetTypeName(target.Type),
+		"values":     target.Values,
+		"resultList": resultList,
+	}).Debug("end to push target msg")
+	return resultList, err
+}
+
+//全量推送，返回厂商任务id
+func BroadcastAllMsg(ctx context.Context, msg *common.Msg, name string) (string, error) {
+	log.WithFields(log.Fields{
+		"name": name,
+		"msg":  msg,
+	}).Debug("begin to broadcast all msg")
+	sdk, _, err := findPushSdk(name, msg.PackageName)
+	if err != nil {
+		log.WithError(err).Errorf("broadcast all msg invalid client name :[%s]", name)
+		return "", err
+	}
+	broadcastSdk, ok := sdk.(BroadcastSdkApi)
+	if !ok {
+		return "", common.NotSupportedTargetError(sdk.Name(), common.NewAllTarget())
+	}
+	if err = validateMsg(sdk, msg); err != nil {
+		log.WithError(err).Errorf("broadcast all msg invalid client name :[%s]", name)
+		return "", err
+	}
+	taskId, err := broadcastSdk.BroadcastAll(msg)
+	//以任务id作为请求id通知观察者，回执按任务id关联到消息
+	target := common.NewAllTarget()
+	var resultList map[string]*common.CallbackResponseItem
+	if err == nil {
+		key := target.Keys()[0]
+		resultList = map[string]*common.CallbackResponseItem{
+			key: {
+				Status:       common.CALLBACK_STATUS_OK,
+				MsgId:        msg.Id,
+				RequestId:    taskId,
+				Token:        key,
+				DeviceVendor: sdk.Name(),
+				PackageName:  msg.PackageName,
+			},
+		}
+	}
+	handleSendResults(sdk.Name(), msg, target, resultList, err)
+	if err != nil {
+		log.WithError(err).Errorf("broadcast all msg error client name :[%s]", name)
+		return taskId, err
+	}
+	log.WithFields(log.Fields{
+		"name":   name,
+		"taskId": taskId,
+	}).Debug("end to broadcast all msg")
+	return taskId, nil
+}
+
+//补充推送结果的事件类型，收集其中的失效token并通知观察者
+//别名、账号、主题等目标的结果以目标值为key，不是设备token，不做失效收集
+func handleSendResults(vendor string, msg *common.Msg, target *common.Target, resultList map[string]*common.CallbackResponseItem, err error) {
+	for _, item := range resultList {
+		if item != nil && item.EventType == "" {
+			item.EventType = common.GetSendEventType(item.Status)
+		}
+	}
+	targets := target.Keys()
+	if target.Type == common.TARGET_TYPE_TOKEN {
+		targets = target.Values
+		collectResultInvalidTokens(vendor, resultList)
+	}
+	notifySendResults(vendor, msg, targets, resultList, err)
+}
+
+func mergeSkippedResults(resultList, skipped map[string]*common.CallbackResponseItem) map[string]*common.CallbackResponseItem {
+	if len(skipped) == 0 {
+		return resultList
+	}
+	if resultList == nil {
+		resultList = make(map[string]*common.CallbackResponseItem, len(skipped))
+	}
+	for token, item := range skipped {
+		resultList[token] = item
 	}
-	return msg
+	return resultList
 }
@@ -0,0 +1,18 @@
+package push_sdks
+
+import (
+	"context"
+	"testing"
+
+	"push_sdks/common"
+)
+
+func TestUnknownClientReturnsError(t *testing.T) {
+	msg := &common.Msg{MsgTitle: "title", PackageName: "com.unknown"}
+	if _, err := PushTargetMsg(context.Background(), msg, "unknown", common.NewAliasTarget("alias")); err == nil {
+		t.Errorf("push target msg to unknown client should fail")
+	}
+	if _, err := BroadcastAllMsg(context.Background(), msg, "unknown"); err == nil {
+		t.Errorf("broadcast all msg to unknown client should fail")
+	}
+}
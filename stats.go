@@ -0,0 +1,29 @@
+package push_sdks
+
+import (
+	"fmt"
+	"time"
+
+	"push_sdks/common"
+)
+
+//支持推送统计查询的厂商实现（小米、vivo、魅族、OPPO）
+type StatsSdkApi interface {
+	//按推送返回的任务id查询统计
+	GetTaskStats(taskIds ...string) ([]*common.TaskStats, error)
+	//按天查询应用推送统计
+	GetDailyStats(start, end time.Time) ([]*common.DailyStats, error)
+}
+
+//按厂商和包名获取统计查询客户端
+func GetStatsSdk(name, packageName string) (StatsSdkApi, error) {
+	sdk, _, err := findPushSdk(name, packageName)
+	if err != nil {
+		return nil, err
+	}
+	statsSdk, ok := sdk.(StatsSdkApi)
+	if !ok {
+		return nil, fmt.Errorf("%s stats: %w", sdk.Name(), common.ErrNotSupported)
+	}
+	return statsSdk, nil
+}
@@ -0,0 +1,153 @@
+package push_sdks
+
+import (
+	"sync"
+	"time"
+
+	"push_sdks/common"
+)
+
+//默认屏蔽时长，过期后token重新参与推送
+const DEFAULT_TOKEN_SUPPRESS_TTL = 7 * 24 * time.Hour
+
+//失效token的状态
+type TokenState struct {
+	DeviceVendor string
+	PackageName  string
+	Token        string
+	Status       int64
+	Reason       string
+	SuppressedAt int64 //秒
+	ExpireAt     int64 //秒
+}
+
+//token状态存储，可替换为redis等实现，Get不应返回已过期的状态
+type TokenStore interface {
+	Get(vendor, token string) (*TokenState, bool)
+	Set(state *TokenState)
+	Delete(vendor, token string)
+}
+
+//内存存储，过期数据在读取时删除
+type MemoryTokenStore struct {
+	lock   sync.RWMutex
+	states map[string]*TokenState
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{states: make(map[string]*TokenState)}
+}
+
+func tokenStateKey(vendor, token string) string {
+	return vendor + "_" + token
+}
+
+func (s *MemoryTokenStore) Get(vendor, token string) (*TokenState, bool) {
+	key := tokenStateKey(vendor, token)
+	s.lock.RLock()
+	state, ok := s.states[key]
+	s.lock.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if state.ExpireAt <= time.Now().Unix() {
+		s.lock.Lock()
+		if cur, ok := s.states[key]; ok && cur == state {
+			delete(s.states, key)
+		}
+		s.lock.Unlock()
+		return nil, false
+	}
+	return state, true
+}
+
+func (s *MemoryTokenStore) Set(state *TokenState) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.states[tokenStateKey(state.DeviceVendor, state.Token)] = state
+}
+
+func (s *MemoryTokenStore) Delete(vendor, token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.states, tokenStateKey(vendor, token))
+}
+
+var tokenStore TokenStore
+var tokenSuppressTTL = DEFAULT_TOKEN_SUPPRESS_TTL
+var tokenStoreLock sync.RWMutex
+
+//开启失效token屏蔽，store为空时关闭；推送结果、回执和feedback中的失效token会自动写入
+func SetTokenStore(store TokenStore, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DEFAULT_TOKEN_SUPPRESS_TTL
+	}
+	tokenStoreLock.Lock()
+	defer tokenStoreLock.Unlock()
+	tokenStore = store
+	tokenSuppressTTL = ttl
+}
+
+func getTokenStore() (TokenStore, time.Duration) {
+	tokenStoreLock.RLock()
+	defer tokenStoreLock.RUnlock()
+	return tokenStore, tokenSuppressTTL
+}
+
+//设备重新上报token时调用，立即解除屏蔽
+func ReviveToken(vendor, token string) {
+	store, _ := getTokenStore()
+	if store == nil {
+		return
+	}
+	store.Delete(vendor, token)
+}
+
+func suppressInvalidTokens(events []*common.InvalidTokenEvent) {
+	store, ttl := getTokenStore()
+	if store == nil {
+		return
+	}
+	now := time.Now().Unix()
+	for _, event := range events {
+		store.Set(&TokenState{
+			DeviceVendor: event.DeviceVendor,
+			PackageName:  event.PackageName,
+			Token:        event.Token,
+			Status:       event.Status,
+			Reason:       event.Reason,
+			SuppressedAt: now,
+			ExpireAt:     now + int64(ttl/time.Second),
+		})
+	}
+}
+
+//过滤已屏蔽的token，被过滤的token以CALLBACK_STATUS_SKIPPED返回
+func filterSuppressedTokens(vendor string, tokens []string) ([]string, map[string]*common.CallbackResponseItem) {
+	store, _ := getTokenStore()
+	if store == nil {
+		return tokens, nil
+	}
+	var skipped map[string]*common.CallbackResponseItem
+	alive := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		state, ok := store.Get(vendor, token)
+		if !ok {
+			alive = append(alive, token)
+			continue
+		}
+		if skipped == nil {
+			skipped = make(map[string]*common.CallbackResponseItem)
+		}
+		skipped[token] = &common.CallbackResponseItem{
+			Status:       common.CALLBACK_STATUS_SKIPPED,
+			EventType:    common.EVENT_TYPE_FILTERED,
+			Token:        token,
+			Timestamp:    time.Now().Unix(),
+			Description:  state.Reason,
+			DeviceVendor: vendor,
+			PackageName:  state.PackageName,
+		}
+	}
+	return alive, skipped
+}
@@ -0,0 +1,56 @@
+package push_sdks
+
+import (
+	"context"
+	"fmt"
+
+	"push_sdks/common"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//支持topic订阅管理的厂商实现（FCM、华为）
+type TopicSdkApi interface {
+	SubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error)
+	UnsubscribeTopic(ctx context.Context, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error)
+}
+
+//订阅topic，返回每个token的结果，其中的失效token会被收集
+func SubscribeTopic(ctx context.Context, name, packageName, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	topicSdk, vendor, err := getTopicSdk(name, packageName)
+	if err != nil {
+		return nil, err
+	}
+	resultList, err := topicSdk.SubscribeTopic(ctx, topic, tokens)
+	collectResultInvalidTokens(vendor, resultList)
+	if err != nil {
+		log.WithError(err).WithField("topic", topic).Errorf("subscribe topic error client name :[%s]", name)
+	}
+	return resultList, err
+}
+
+//取消订阅topic，返回每个token的结果，其中的失效token会被收集
+func UnsubscribeTopic(ctx context.Context, name, packageName, topic string, tokens []string) (map[string]*common.CallbackResponseItem, error) {
+	topicSdk, vendor, err := getTopicSdk(name, packageName)
+	if err != nil {
+		return nil, err
+	}
+	resultList, err := topicSdk.UnsubscribeTopic(ctx, topic, tokens)
+	collectResultInvalidTokens(vendor, resultList)
+	if err != nil {
+		log.WithError(err).WithField("topic", topic).Errorf("unsubscribe topic error client name :[%s]", name)
+	}
+	return resultList, err
+}
+
+func getTopicSdk(name, packageName string) (TopicSdkApi, string, error) {
+	sdk, _, err := findPushSdk(name, packageName)
+	if err != nil {
+		return nil, "", err
+	}
+	topicSdk, ok := sdk.(TopicSdkApi)
+	if !ok {
+		return nil, "", fmt.Errorf("%s topic: %w", sdk.Name(), common.ErrNotSupported)
+	}
+	return topicSdk, sdk.Name(), nil
+}
@@ -0,0 +1,47 @@
+package push_sdks
+
+import (
+	"context"
+	"fmt"
+
+	"push_sdks/common"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//支持发送前校验消息的厂商实现，返回全部不符合厂商规则的字段
+type MsgValidator interface {
+	ValidateMsg(msg *common.Msg) []*common.Violation
+}
+
+//推送前校验原始消息，不通过时返回*common.ValidationError，不调用厂商接口；提示性规则只记录日志
+func validateMsg(sdk SdkApi, msg *common.Msg) error {
+	validator, ok := sdk.(MsgValidator)
+	if !ok {
+		return nil
+	}
+	violations := validator.ValidateMsg(msg)
+	for _, v := range violations {
+		if v.Warning {
+			log.WithField("msgId", msg.Id).Warnf("validate msg warning: %s", v)
+		}
+	}
+	return common.NewValidationError(violations)
+}
+
+//只校验不发送，返回消息在指定厂商下不符合规则的字段，消息本身不会被修改
+func DryRun(ctx context.Context, msg *common.Msg, name string) ([]*common.Violation, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("empty msg client name :[%s]", name)
+	}
+	sdk := GetPushSdkByName(msg.PackageName, name)
+	if sdk == nil {
+		return nil, fmt.Errorf("push client not found name :[%s]", name)
+	}
+	validator, ok := sdk.(MsgValidator)
+	if !ok {
+		return nil, nil
+	}
+	copied := *msg
+	return validator.ValidateMsg(&copied), nil
+}
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -57,16 +58,16 @@ func NewClient(cfg config.PushServerCfg) (*VivoPush, error) {
 }
 
 //----------------------------------------Token----------------------------------------//
+//鉴权签名 md5(appId+appKey+timestamp+appSecret)，回执签名使用相同规则
+func (vc *VivoPush) sign(timestamp string) string {
+	sum := md5.Sum([]byte(vc.cfg.AppId + vc.cfg.AppKey + timestamp + vc.cfg.AppSecret))
+	return hex.EncodeToString(sum[:])
+}
+
 //获取token  返回的expiretime 秒  当过期的时候
 func (vc *VivoPush) GetToken() (string, int, error) {
 	now := time.Now().UnixNano() / 1e6
-	md5Ctx := md5.New()
-	n, err := md5Ctx.Write([]byte(vc.cfg.AppId + vc.cfg.AppKey + strconv.FormatInt(now, 10) + vc.cfg.AppSecret))
-	if err != nil {
-		log.WithError(err).WithField("n", n).Error("md5 error")
-		return "", 0, err
-	}
-	sign := hex.EncodeToString(md5Ctx.Sum(nil))
+	sign := vc.sign(strconv.FormatInt(now, 10))
 
 	formData, err := json.Marshal(&VivoTokenPar{
 		AppId:     vc.cfg.AppId,
@@ -118,6 +119,15 @@ func (vc *VivoPush) Name() string {
 }
 
 func (vc *VivoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	return vc.PushTargetMsg(msg, common.NewTokenTarget(tokens))
+}
+
+//按目标类型推送，支持regId和别名
+func (vc *VivoPush) PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	if target.Type != common.TARGET_TYPE_TOKEN && target.Type != common.TARGET_TYPE_ALIAS {
+		return nil, common.NotSupportedTargetError(vc.cfg.Name, target)
+	}
+	tokens := target.Values
 	if len(tokens) == 0 {
 		return nil, nil
 	}
@@ -151,13 +161,16 @@ func (vc *VivoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[
 		formatMsg.SkipContent = msg.MsgAction
 		if vc.cfg.Redirect != "" {
 			formatMsg.Extra = make(map[string]string, 2)
-			formatMsg.Extra["callback"] = vc.cfg.Redirect + fmt.Sprintf("?deviceVendor=%s", vc.Name())
-			params := common.CallbackParam{MsgId: msg.Id, Package: vc.cfg.Package}
-			paramsData, err := json.Marshal(params)
+			formatMsg.Extra["callback"] = common.CallbackUrl(vc.cfg.Redirect, vc.Name(), vc.cfg.Package, vc.cfg.CallbackToken)
+			paramsData, dropped, err := common.NewCallbackParam(msg, vc.cfg.Package).Encode(vc.cfg.CallbackSecret, vc.cfg.CallbackExpire, MaxCallbackParamLength)
 			if err != nil {
-				return nil, err
+				log.WithError(err).WithField("msgId", msg.Id).Warnf("%s callback param too long, send without it", vc.cfg.Name)
+			} else if len(dropped) > 0 {
+				log.WithField("msgId", msg.Id).WithField("dropped", dropped).Warnf("%s callback extra dropped by length limit", vc.cfg.Name)
+			}
+			if paramsData != "" {
+				formatMsg.Extra["callback.param"] = paramsData
 			}
-			formatMsg.Extra["callback.param"] = string(paramsData)
 		}
 
 		formatMsg.Classification = 1 //系统消息
@@ -168,7 +181,7 @@ func (vc *VivoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[
 		formatMsg.RequestId = fmt.Sprintf("%d", msg.Id)
 		var result *SendResult
 		for i := 0; i < 2; i++ {
-			result, err = vc.send(formatMsg, tokens[0])
+			result, err = vc.send(formatMsg, target.Type, tokens[0])
 			if result != nil {
 				//VIVO正式应用发送的title及content里面不能是纯数字、纯英文、纯符号、符号加数字，包含“测试”字样、大括号、中括号 。
 				if result.Result == 10104 || result.Result == 10085 {
@@ -202,7 +215,7 @@ func (vc *VivoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[
 	formatMsg.SkipContent = msg.MsgAction
 	formatMsg.RequestId = fmt.Sprintf("%d", msg.Id)
 
-	result, err := vc.sendList(formatMsg, tokens)
+	result, err := vc.sendList(formatMsg, target.Type, tokens)
 	//暂时屏蔽vivo regId不合法和发送超出时间限制, 运营消息总量超出， 系统消息总量超出
 	if result != nil && (result.Result == 10302 || result.Result == 10071 || result.Result == 10070 || result.Result == 10073) {
 		err = nil
@@ -216,6 +229,88 @@ func (vc *VivoPush) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[
 	return failsInfoMap, nil
 }
 
+//全量推送，返回任务id，可用于统计查询
+func (vc *VivoPush) BroadcastAll(msg *common.Msg) (string, error) {
+	formatMsg := NewListPayloadMessage(msg.MsgTitle, msg.MsgBody)
+	formatMsg.SkipType = 1
+	formatMsg.SkipContent = msg.MsgAction
+	formatMsg.RequestId = fmt.Sprintf("%d", msg.Id)
+
+	result, err := vc.SendAll(formatMsg)
+	if err != nil {
+		log.WithField("msgInfo", msg).WithError(err).Errorf("vivo broadcast all msg error")
+		return "", err
+	}
+	log.WithField("msgInfo", msg).Infof("vivo broadcast all msg result: [%v] ", result)
+	return result.TaskId, nil
+}
+
+//按任务id查询推送统计，单次最多100个任务
+func (vc *VivoPush) GetTaskStats(taskIds ...string) ([]*common.TaskStats, error) {
+	res, err := vc.GetMessageStatusByJobKey(strings.Join(taskIds, ","))
+	if err != nil {
+		return nil, fmt.Errorf("%s get task stats error:[%v]", vc.cfg.Name, err)
+	}
+	if res.Result != 0 {
+		return nil, fmt.Errorf("%s get task stats error:[%d %s]", vc.cfg.Name, res.Result, res.Desc)
+	}
+	list := make([]*common.TaskStats, 0, len(res.Statistics))
+	for _, data := range res.Statistics {
+		list = append(list, &common.TaskStats{
+			TaskId:       data.TaskId,
+			DeviceVendor: vc.cfg.Name,
+			PackageName:  vc.cfg.Package,
+			Sent:         int64(data.Send),
+			Delivered:    int64(data.Receive),
+			Displayed:    int64(data.Display),
+			Clicked:      int64(data.Click),
+		})
+	}
+	return list, nil
+}
+
+//vivo没有按天统计接口
+func (vc *VivoPush) GetDailyStats(start, end time.Time) ([]*common.DailyStats, error) {
+	return nil, fmt.Errorf("%s daily stats: %w", vc.cfg.Name, common.ErrNotSupported)
+}
+
+//撤回消息，RequestId为推送返回的taskId
+func (vc *VivoPush) Recall(ctx context.Context, requests ...*common.RecallRequest) ([]*common.RecallResult, error) {
+	list := make([]*common.RecallResult, 0, len(requests))
+	for _, request := range requests {
+		if err := ctx.Err(); err != nil {
+			return list, err
+		}
+		item := &common.RecallResult{DeviceVendor: vc.cfg.Name, PackageName: vc.cfg.Package, RequestId: request.RequestId}
+		result, err := vc.recall(request.RequestId)
+		if err != nil {
+			item.Description = err.Error()
+		} else {
+			item.Success = result.Result == 0
+			item.Description = result.Desc
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func (vc *VivoPush) recall(taskId string) (*ResultItem, error) {
+	body, err := json.Marshal(map[string]string{"taskId": taskId})
+	if err != nil {
+		return nil, err
+	}
+	res, err := vc.doPost(vc.host+RecallURL, body)
+	if err != nil {
+		return nil, err
+	}
+	var result ResultItem
+	err = json.Unmarshal(res, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 func (vc *VivoPush) ResultItemFormat(result *SendResult, tokens []string) map[string]*common.CallbackResponseItem {
 	if result == nil {
 		return nil
@@ -241,6 +336,10 @@ func (vc *VivoPush) ResultItemFormat(result *SendResult, tokens []string) map[st
 	default:
 		status = int64(result.Result)
 	}
+	//批量推送返回的是整批的结果，不能据此判定每个token都失效，保留vivo原始错误码
+	if len(tokens) > 1 && common.IsInvalidTokenStatus(status) {
+		status = int64(result.Result)
+	}
 	failsInfoMap := make(map[string]*common.CallbackResponseItem, len(tokens))
 	for _, token := range tokens {
 		res := &common.CallbackResponseItem{
@@ -253,29 +352,72 @@ func (vc *VivoPush) ResultItemFormat(result *SendResult, tokens []string) map[st
 		}
 		failsInfoMap[token] = res
 	}
+	//整批成功时按invalidUsers标记单个token的状态
+	for _, user := range result.InvalidUsers {
+		res, ok := failsInfoMap[user.UserId]
+		if !ok {
+			continue
+		}
+		res.Status = formatInvalidUserStatus(user.Status)
+		res.Description = fmt.Sprintf("invalid user status:%d", user.Status)
+	}
 	return failsInfoMap
 }
 
+func formatInvalidUserStatus(status int) int64 {
+	switch status {
+	case 1:
+		return common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN
+	case 2: //vivo不区分卸载和关闭推送，按卸载处理
+		return common.CALLBACK_STATUS_UNINSTALL_APP
+	case 3:
+		return common.CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN
+	case 4: //非测试用户
+		return common.CALLBACK_STATUS_FILTERED
+	}
+	return common.CALLBACK_STATUS_NEED_RETRY
+}
+
 type CallBackItem struct {
 	Param   string `json:"param"`
 	Targets string `json:"targets"`
 }
 
+//回执签名请求头
+const (
+	CALLBACK_TIMESTAMP_HEADER = "timestamp"
+	CALLBACK_SIGN_HEADER      = "sign"
+)
+
+//开启callback_sign时校验请求头中的签名，再校验回执地址中的token
+func (vc *VivoPush) VerifyCallback(r *http.Request) error {
+	if vc.cfg.CallbackSign {
+		timestamp := r.Header.Get(CALLBACK_TIMESTAMP_HEADER)
+		if err := common.CheckCallbackTimestamp(vc.cfg.Name, timestamp); err != nil {
+			return err
+		}
+		if err := common.VerifyCallbackSign(vc.cfg.Name, r.Header.Get(CALLBACK_SIGN_HEADER), vc.sign(timestamp)); err != nil {
+			return err
+		}
+	}
+	return common.VerifyCallbackToken(r, vc.cfg.Name, vc.cfg.CallbackToken)
+}
+
 func (vc *VivoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
 	res := map[string]interface{}{"errno": 0, "errmsg": "success"}
 	requestBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields{
 			"deviceVendor": vc.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
 		}).Errorf("invalid request body")
 		return nil, nil, err
 	}
 	log.WithFields(log.Fields{
 		"deviceVendor": vc.cfg.Name,
-		"url":          r.URL.RequestURI(),
-		"header":       r.Header,
+		"url":          common.RedactUri(r),
+		"header":       common.RedactHeader(r),
 	}).Tracef("callback")
 
 	var reciver map[string]CallBackItem
@@ -283,8 +425,8 @@ func (vc *VivoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[
 	if err != nil {
 		log.WithFields(log.Fields{
 			"deviceVendor": vc.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
 			"form":         string(requestBody),
 		}).Errorf("empty params data")
 		return nil, nil, err
@@ -292,8 +434,7 @@ func (vc *VivoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[
 	var callbackResponse common.CallbackResponse
 	for requestId, val := range reciver {
 		item := &common.CallbackResponseItem{}
-		params := common.CallbackParam{}
-		err := json.Unmarshal([]byte(val.Param), &params)
+		params, err := common.DecodeCallbackParam(vc.cfg.Name, val.Param, vc.cfg.CallbackSecret)
 		if err != nil {
 			log.WithError(err).WithField("deviceVendor", vc.cfg.Name).Errorf("invalid callback param: %v", val.Param)
 			continue
@@ -303,7 +444,12 @@ func (vc *VivoPush) PushReciver(r *http.Request) (*common.CallbackResponse, map[
 		item.RequestId = requestId
 		item.Timestamp = time.Now().UnixNano() / 1000
 		item.PackageName = params.Package
+		if item.PackageName == "" {
+			item.PackageName = vc.cfg.Package
+		}
+		item.Extra = params.Extra
 		item.Status = common.CALLBACK_STATUS_OK
+		item.EventType = common.EVENT_TYPE_DELIVERED //vivo只有送达回执
 		callbackResponse.Data = append(callbackResponse.Data, item)
 	}
 	return &callbackResponse, res, nil
@@ -318,9 +464,9 @@ func (c *VivoPush) RegisterDeviceToken(deviceId string) (deviceToken string, err
 }
 
 //----------------------------------------Sender----------------------------------------//
-// 根据regID，发送消息到指定设备上
-func (v *VivoPush) send(msg *Message, regID string) (*SendResult, error) {
-	params := v.assembleSendParams(msg, regID)
+// 根据regID或别名，发送消息到指定设备上
+func (v *VivoPush) send(msg *Message, targetType int, target string) (*SendResult, error) {
+	params := v.assembleSendParams(msg, targetType, target)
 	res, err := v.doPost(v.host+SendURL, params)
 	if err != nil {
 		return nil, err
@@ -354,7 +500,7 @@ func (v *VivoPush) SaveListPayload(msg *MessagePayload) (*SendResult, error) {
 }
 
 // 群推
-func (v *VivoPush) sendList(msg *MessagePayload, regIds []string) (*SendResult, error) {
+func (v *VivoPush) sendList(msg *MessagePayload, targetType int, regIds []string) (*SendResult, error) {
 	if len(regIds) < 2 || len(regIds) > 1000 {
 		return nil, errors.New("regIds个数必须大于等于2,小于等于 1000")
 	}
@@ -366,6 +512,9 @@ func (v *VivoPush) sendList(msg *MessagePayload, regIds []string) (*SendResult,
 		return res, errors.New(res.Desc)
 	}
 	msgList := NewListMessage(regIds, res.TaskId)
+	if targetType == common.TARGET_TYPE_ALIAS {
+		msgList = NewAliasListMessage(regIds, res.TaskId)
+	}
 	msgList.PushMode = v.pushMod
 
 	bytes, err := json.Marshal(msgList)
@@ -421,8 +570,12 @@ func (v *VivoPush) GetMessageStatusByJobKey(jobKey string) (*BatchStatusResult,
 	return &result, nil
 }
 
-func (v *VivoPush) assembleSendParams(msg *Message, regID string) []byte {
-	msg.RegId = regID
+func (v *VivoPush) assembleSendParams(msg *Message, targetType int, target string) []byte {
+	if targetType == common.TARGET_TYPE_ALIAS {
+		msg.Alias = target
+	} else {
+		msg.RegId = target
+	}
 	jsondata := msg.JSON()
 	return jsondata
 }
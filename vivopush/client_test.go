@@ -0,0 +1,58 @@
+package vivopush
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+func TestResultItemFormat(t *testing.T) {
+	vc := &VivoPush{cfg: &config.PushServerCfg{Name: "vivo"}}
+	result := &SendResult{ResultItem: ResultItem{Result: 10302, Desc: "regId不合法"}}
+
+	single := vc.ResultItemFormat(result, []string{"a"})
+	if single["a"].Status != common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN {
+		t.Errorf("unexpected single result %+v", single["a"])
+	}
+
+	batch := vc.ResultItemFormat(result, []string{"a", "b"})
+	for token, item := range batch {
+		if common.IsInvalidTokenStatus(item.Status) {
+			t.Errorf("batch result of %s should not be an invalid token status %+v", token, item)
+		}
+	}
+}
+
+func TestVerifyCallback(t *testing.T) {
+	vc := &VivoPush{cfg: &config.PushServerCfg{Name: "vivo", AppId: "1", AppKey: "key", AppSecret: "secret", CallbackSign: true}}
+	timestamp := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+	r := httptest.NewRequest("POST", "/callback", nil)
+	r.Header.Set(CALLBACK_TIMESTAMP_HEADER, timestamp)
+	r.Header.Set(CALLBACK_SIGN_HEADER, vc.sign(timestamp))
+	if err := vc.VerifyCallback(r); err != nil {
+		t.Errorf("valid callback sign rejected: %v", err)
+	}
+	r.Header.Set(CALLBACK_SIGN_HEADER, "forged")
+	if err := vc.VerifyCallback(r); !errors.Is(err, common.ErrCallbackUnauthorized) {
+		t.Errorf("forged callback sign accepted: %v", err)
+	}
+}
+
+func TestResultItemFormatInvalidUsers(t *testing.T) {
+	vc := &VivoPush{cfg: &config.PushServerCfg{Name: "vivo"}}
+	result := &SendResult{ResultItem: ResultItem{Result: 0, Desc: "请求成功"}, TaskId: "t1",
+		InvalidUsers: []InvalidUser{{Status: 1, UserId: "a"}, {Status: 3, UserId: "c"}}}
+
+	batch := vc.ResultItemFormat(result, []string{"a", "b", "c"})
+	if batch["a"].Status != common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN || batch["c"].Status != common.CALLBACK_STATUS_INACTIVE_DEVICE_TOKEN {
+		t.Errorf("invalid users should be marked invalid %+v %+v", batch["a"], batch["c"])
+	}
+	if batch["b"].Status != common.CALLBACK_STATUS_OK || batch["b"].RequestId != "t1" {
+		t.Errorf("valid user should keep batch status %+v", batch["b"])
+	}
+}
@@ -10,7 +10,12 @@ const (
 	SaveListPayloadURL = "/message/saveListPayload" // 保存群推消息公共体接口
 	PushToListURL      = "/message/pushToList"      // 批量推送用户接口
 	PushToAllURL       = "/message/all"             // 全量发送接口
-	MessagesStatusURL  = "/report/getStatistics "   // 获取消息推送的统计值接口
+	MessagesStatusURL  = "/report/getStatistics"    // 获取消息推送的统计值接口
+	RecallURL          = "/message/recall"          // 撤回消息接口
+)
+
+const (
+	MaxCallbackParamLength = 64 // callback.param 最大长度
 )
 
 var (
@@ -9,7 +9,8 @@ import (
 
 //单推
 type Message struct {
-	RegId               string            `json:"regId"`           // 订阅 PUSH 服务器得到的 id
+	RegId               string            `json:"regId,omitempty"` // 订阅 PUSH 服务器得到的 id
+	Alias               string            `json:"alias,omitempty"` // 别名 regId和alias两者需一个不为空，两个不为空时，取regId
 	NotifyType          int               `json:"notifyType"`      // 通知类型 1:无，2:响铃，3:振动，4:响铃和振动
 	Title               string            `json:"title"`           // 通知标题
 	Content             string            `json:"content"`         // 通知内容
@@ -41,9 +42,10 @@ type MessagePayload struct {
 
 //群推
 type MessageList struct {
-	RegIds    []string `json:"regIds"`    // regId 列表 个数大于等于 2，小于等于 1000， regId 长度 23 个字符(regIds，aliases 两者需 一个不为空，两个不为空，取 regIds)
-	TaskId    string   `json:"taskId"`    // 公共消息任务号，取 saveListPayload 返回的 taskId
-	RequestId string   `json:"requestId"` // 用户请求唯一标识
+	RegIds    []string `json:"regIds,omitempty"`  // regId 列表 个数大于等于 2，小于等于 1000， regId 长度 23 个字符(regIds，aliases 两者需 一个不为空，两个不为空，取 regIds)
+	Aliases   []string `json:"aliases,omitempty"` // 别名列表 个数大于等于 2，小于等于 1000
+	TaskId    string   `json:"taskId"`            // 公共消息任务号，取 saveListPayload 返回的 taskId
+	RequestId string   `json:"requestId"`         // 用户请求唯一标识
 	PushMode  int      `json:"pushMode"`
 }
 
@@ -120,6 +122,15 @@ func NewListMessage(regIds []string, taskId string) *MessageList {
 	}
 }
 
+// 发送群推消息给aliases
+func NewAliasListMessage(aliases []string, taskId string) *MessageList {
+	return &MessageList{
+		Aliases:   aliases,
+		TaskId:    taskId,
+		RequestId: strings.ToUpper(gouuid.Must(gouuid.NewV4()).String()),
+	}
+}
+
 // 打开当前app首页
 func (m *Message) SetLauncherActivity() *Message {
 	m.SkipType = 1
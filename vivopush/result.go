@@ -1,24 +1,31 @@
-package vivopush
-
-type ResultItem struct {
-	Result int    `json:"result"`
-	Desc   string `json:"desc"`
-}
-
-type SendResult struct {
-	ResultItem
-	TaskId string `json:"taskId"`
-}
-
-type BatchStatusResult struct {
-	ResultItem
-	Statistics []TaskData `json:"statistics"`
-}
-
-type TaskData struct {
-	TaskId  string `json:"taskId"`
-	Send    int    `json:"send"`
-	Receive int    `json:"receive"`
-	Display int    `json:"display"`
-	Click   int    `json:"click"`
-}
+package vivopush
+
+type ResultItem struct {
+	Result int    `json:"result"`
+	Desc   string `json:"desc"`
+}
+
+type SendResult struct {
+	ResultItem
+	TaskId       string        `json:"taskId"`
+	InvalidUsers []InvalidUser `json:"invalidUsers"` //批量推送中的无效用户
+}
+
+//批量推送返回的无效用户，status 1:userId不存在 2:卸载或关闭了推送 3:90天不在线 4:非测试用户
+type InvalidUser struct {
+	Status int    `json:"status"`
+	UserId string `json:"userid"`
+}
+
+type BatchStatusResult struct {
+	ResultItem
+	Statistics []TaskData `json:"statistics"`
+}
+
+type TaskData struct {
+	TaskId  string `json:"taskId"`
+	Send    int    `json:"send"`
+	Receive int    `json:"receive"`
+	Display int    `json:"display"`
+	Click   int    `json:"click"`
+}
@@ -0,0 +1,18 @@
+package vivopush
+
+import (
+	"push_sdks/common"
+)
+
+//vivo消息限制：标题40字符，内容100字符，跳转内容1024字符，超出时服务端返回10104、10085等错误
+var MsgRules = common.RuleSet{
+	common.RequiredRule("title", common.MsgTitleField),
+	common.MaxLengthRule("title", 40, common.MsgTitleField),
+	common.RequiredRule("content", common.MsgBodyField),
+	common.MaxLengthRule("content", 100, common.MsgBodyField),
+	common.MaxLengthRule("skipContent", 1024, common.MsgActionField),
+}
+
+func (vc *VivoPush) ValidateMsg(msg *common.Msg) []*common.Violation {
+	return MsgRules.Validate(vc.cfg.Name, msg)
+}
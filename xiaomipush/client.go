@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -403,13 +404,17 @@ func (m *MiPush) UploadImg(ctx context.Context, imgUrl string) (*MediaResult, er
 	if fileName == "" {
 		return nil, nil
 	}
-	imgUrl += "?x-oss-process=image/resize,limit_0,m_fill,l_876,s_324/quality,q_100"
-	path := os.TempDir()
-	filePath, err := common.DowlodPic(imgUrl, path, "", fileName)
+	filePath, err := common.PrepareImage(ctx, imgUrl, common.XiaomiBigPictureSpec)
 	if err != nil {
 		return nil, err
 	}
-	bytes, err := m.doUpload(ctx, m.host+MediaUploadImageURL, filePath, fileName)
+	defer os.Remove(filePath)
+	return m.UploadImgFile(ctx, filePath)
+}
+
+// 上传本地图片，图片需已符合大图规格
+func (m *MiPush) UploadImgFile(ctx context.Context, filePath string) (*MediaResult, error) {
+	bytes, err := m.doUpload(ctx, m.host+MediaUploadImageURL, filePath, filepath.Base(filePath))
 	if err != nil {
 		return nil, err
 	}
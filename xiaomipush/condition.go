@@ -0,0 +1,64 @@
+package xiaomipush
+
+import (
+	"errors"
+	"fmt"
+
+	"push_sdks/common"
+)
+
+// 多topic广播最多支持5个topic
+const MaxConditionTopics = 5
+
+// 将条件表达式编译为多topic广播参数，小米只支持一种运算：
+// a||b||c 编译为 UNION，a&&b&&c 编译为 INTERSECTION，a&&!b&&!c 编译为 EXCEPT (a-b-c)
+// 单个topic返回空的TopicOP
+func CompileCondition(condition *common.Condition) ([]string, TopicOP, error) {
+	if err := condition.Validate(); err != nil {
+		return nil, "", err
+	}
+	if condition.Op == common.CONDITION_OP_TOPIC {
+		return []string{condition.Topic}, "", nil
+	}
+	if len(condition.Operands) > MaxConditionTopics {
+		return nil, "", fmt.Errorf("xiaomi condition supports at most %d topics, got %d", MaxConditionTopics, len(condition.Operands))
+	}
+	topics := make([]string, 0, len(condition.Operands))
+	switch condition.Op {
+	case common.CONDITION_OP_OR:
+		for _, operand := range condition.Operands {
+			if operand.Op != common.CONDITION_OP_TOPIC {
+				return nil, "", errors.New("xiaomi condition only supports a single operator over topics")
+			}
+			topics = append(topics, operand.Topic)
+		}
+		return topics, UNION, nil
+	case common.CONDITION_OP_AND:
+		first := condition.Operands[0]
+		if first.Op != common.CONDITION_OP_TOPIC {
+			return nil, "", errors.New("xiaomi condition only supports a single operator over topics")
+		}
+		topics = append(topics, first.Topic)
+		negated := 0
+		for _, operand := range condition.Operands[1:] {
+			switch {
+			case operand.Op == common.CONDITION_OP_TOPIC:
+				topics = append(topics, operand.Topic)
+			case operand.Op == common.CONDITION_OP_NOT && operand.Operands[0].Op == common.CONDITION_OP_TOPIC:
+				topics = append(topics, operand.Operands[0].Topic)
+				negated++
+			default:
+				return nil, "", errors.New("xiaomi condition only supports a single operator over topics")
+			}
+		}
+		if negated == 0 {
+			return topics, INTERSECTION, nil
+		}
+		if negated == len(condition.Operands)-1 {
+			return topics, EXCEPT, nil
+		}
+		return nil, "", errors.New("xiaomi condition can not mix intersection and except")
+	default:
+		return nil, "", errors.New("xiaomi condition does not support a leading 'not'")
+	}
+}
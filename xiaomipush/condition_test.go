@@ -0,0 +1,29 @@
+package xiaomipush
+
+import (
+	"testing"
+
+	"push_sdks/common"
+)
+
+func TestCompileCondition(t *testing.T) {
+	a, b, c := common.TopicCondition("a"), common.TopicCondition("b"), common.TopicCondition("c")
+
+	topics, op, err := CompileCondition(common.AndCondition(a, common.NotCondition(b), common.NotCondition(c)))
+	if err != nil || op != EXCEPT || len(topics) != 3 {
+		t.Errorf("except condition: topics %v op %v err %v", topics, op, err)
+	}
+
+	topics, op, err = CompileCondition(common.OrCondition(a, b))
+	if err != nil || op != UNION || len(topics) != 2 {
+		t.Errorf("union condition: topics %v op %v err %v", topics, op, err)
+	}
+
+	if _, _, err = CompileCondition(common.AndCondition(a, common.OrCondition(b, c))); err == nil {
+		t.Error("nested operators should be rejected")
+	}
+
+	if _, _, err = CompileCondition(common.AndCondition(a, b, common.NotCondition(c))); err == nil {
+		t.Error("mixed intersection and except should be rejected")
+	}
+}
@@ -1,5 +1,7 @@
 package xiaomipush
 
+import "time"
+
 const (
 	ProductionHost = "https://api.xmpush.xiaomi.com"
 )
@@ -107,3 +109,11 @@ var (
 		"4000+":     "4000+",
 	}
 )
+
+const (
+	MaxCallbackParamLength = 64 // callback.param 最大长度
+)
+
+const (
+	MediaCacheTTL = 24 * time.Hour // 上传图片的缓存时长
+)
@@ -0,0 +1,19 @@
+package xiaomipush
+
+import (
+	"push_sdks/common"
+)
+
+//小米消息限制：标题50字符，描述128字符，payload 4KB
+var MsgRules = common.RuleSet{
+	common.RequiredRule("title", common.MsgTitleField),
+	common.MaxLengthRule("title", 50, common.MsgTitleField),
+	common.RequiredRule("description", common.MsgBodyField),
+	common.MaxLengthRule("description", 128, common.MsgBodyField),
+	common.MaxBytesRule("payload", 4096, func(msg *common.Msg) (interface{}, error) { return msg.MsgAction, nil }),
+	common.UrlRule("img_url", common.MsgImgUrlField, "http", "https"),
+}
+
+func (m *Client) ValidateMsg(msg *common.Msg) []*common.Violation {
+	return MsgRules.Validate(m.cfg.Name, msg)
+}
@@ -4,6 +4,9 @@ import (
 	"push_sdks/config"
 	"push_sdks/common"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -52,38 +55,69 @@ func (m *Client) GetToken() (string, int, error) {
 }
 
 func (m *Client) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
-	msg1 := NewAndroidMessage(msg.MsgTitle, msg.MsgBody).SetPayload(msg.MsgAction).SetNotifyID(msg.Id).SetTimeToSend(time.Now().Unix() * 1000)
-	params := common.CallbackParam{MsgId: msg.Id, Package: m.cfg.Package}
-	paramsData, err := json.Marshal(params)
+	return m.PushTargetMsg(msg, common.NewTokenTarget(tokens))
+}
+
+//按目标类型推送 token(regId)、别名、账号、topic、全量
+func (m *Client) PushTargetMsg(msg *common.Msg, target *common.Target) (failsInfoMap map[string]*common.CallbackResponseItem, err error) {
+	msg1, err := m.formatMsg(msg)
 	if err != nil {
 		return nil, err
 	}
-	query := fmt.Sprintf("?deviceVendor=%s", m.Name())
-	msg1 = msg1.SetCallback(m.cfg.Redirect+query, string(paramsData))
-	if msg.ImgUrl != "" {
-		result, err := m.mipush.UploadImg(context.TODO(), msg.ImgUrl)
-		if err != nil {
-			log.WithError(err).WithFields(log.Fields{
-				"imgUrl": msg.ImgUrl,
-				"result": result,
-			}).Errorf("%s upload img error", m.cfg.Name)
+	ctx := context.Background()
+	var res *SendResult
+	switch target.Type {
+	case common.TARGET_TYPE_TOKEN:
+		res, err = m.mipush.SendToList(ctx, msg1, target.Values)
+	case common.TARGET_TYPE_ALIAS:
+		if len(target.Values) == 0 || len(target.Values) > 1000 {
+			return nil, fmt.Errorf("%s wrong number aliasList", m.cfg.Name)
 		}
-		if result != nil && result.Data.PicUrl != "" {
-			msg1.Extra["notification_bigPic_uri"] = result.Data.PicUrl
-			msg1.Extra["notification_style_type"] = "2"
+		res, err = m.mipush.SendToAliasList(ctx, msg1, target.Values)
+	case common.TARGET_TYPE_USER_ACCOUNT:
+		if len(target.Values) == 0 || len(target.Values) > 1000 {
+			return nil, fmt.Errorf("%s wrong number accountList", m.cfg.Name)
+		}
+		res, err = m.mipush.SendToUserAccountList(ctx, msg1, target.Values)
+	case common.TARGET_TYPE_TOPIC:
+		if len(target.Values) == 0 || len(target.Values) > 5 {
+			return nil, fmt.Errorf("%s topics size invalid", m.cfg.Name)
 		}
+		if len(target.Values) == 1 {
+			res, err = m.mipush.Broadcast(ctx, msg1, target.Values[0])
+		} else {
+			res, err = m.mipush.MultiTopicBroadcast(ctx, msg1, target.Values, UNION)
+		}
+	case common.TARGET_TYPE_ALL:
+		res, err = m.mipush.BroadcastAll(ctx, msg1)
+	case common.TARGET_TYPE_CONDITION:
+		topics, topicOP, compileErr := CompileCondition(target.Condition)
+		if compileErr != nil {
+			return nil, fmt.Errorf("%s invalid condition:[%v]", m.cfg.Name, compileErr)
+		}
+		if topicOP == "" {
+			res, err = m.mipush.Broadcast(ctx, msg1, topics[0])
+		} else {
+			res, err = m.mipush.MultiTopicBroadcast(ctx, msg1, topics, topicOP)
+		}
+	default:
+		return nil, common.NotSupportedTargetError(m.cfg.Name, target)
 	}
-	msg1.Extra["channel_id"] = msg.ChannelID
-	res, err := m.mipush.SendToList(context.Background(), msg1, tokens)
+	keys := target.Keys()
 	if res != nil {
 		if failsInfoMap == nil {
-			failsInfoMap = make(map[string]*common.CallbackResponseItem, len(tokens))
+			failsInfoMap = make(map[string]*common.CallbackResponseItem, len(keys))
+		}
+		//消息id与回执中的id一致，可用于撤回和统计；失败时没有消息id使用trace_id
+		requestId := res.Data.ID
+		if requestId == "" {
+			requestId = res.MessageID
 		}
-		for _, token := range tokens {
+		for _, token := range keys {
 			failsInfoMap[token] = &common.CallbackResponseItem{
 				Status:       res.Code,
 				Description:  res.Reason,
-				RequestId:    res.MessageID,
+				RequestId:    requestId,
 				Token:        token,
 				DeviceVendor: m.cfg.Name,
 				PackageName:  m.cfg.Package,
@@ -93,10 +127,151 @@ func (m *Client) PushMsg(msg *common.Msg, tokens []string) (failsInfoMap map[str
 	if err != nil {
 		return failsInfoMap, fmt.Errorf("%s broadcast msg error:[%v]", m.cfg.Name, err)
 	}
-	log.WithFields(log.Fields{"name": m.Name(), "msgInfo": *msg1, "tokens": tokens, "err": err}).Debugf("push msg result: %v", res)
+	log.WithFields(log.Fields{"name": m.Name(), "msgInfo": *msg1, "target": common.GetTargetTypeName(target.Type), "tokens": keys, "err": err}).Debugf("push msg result: %v", res)
 	return failsInfoMap, err
 }
 
+//全量推送，返回消息id，可用于统计查询
+func (m *Client) BroadcastAll(msg *common.Msg) (string, error) {
+	msg1, err := m.formatMsg(msg)
+	if err != nil {
+		return "", err
+	}
+	res, err := m.mipush.BroadcastAll(context.Background(), msg1)
+	if err != nil {
+		return "", fmt.Errorf("%s broadcast all msg error:[%v]", m.cfg.Name, err)
+	}
+	if res.Code != 0 {
+		return "", fmt.Errorf("%s broadcast all msg error code:[%d] reason:[%s]", m.cfg.Name, res.Code, res.Reason)
+	}
+	log.WithFields(log.Fields{"name": m.Name(), "msgInfo": *msg1}).Debugf("broadcast all msg result: %v", res)
+	return res.Data.ID, nil
+}
+
+//拉取失效的regId，小米返回后即从服务端删除，调用方需自行保存返回结果
+func (m *Client) FetchInvalidTokens() ([]*common.InvalidTokenEvent, error) {
+	res, err := m.mipush.GetInvalidRegIDs(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%s fetch invalid regIds error:[%v]", m.cfg.Name, err)
+	}
+	if res.Code != 0 {
+		return nil, fmt.Errorf("%s fetch invalid regIds error:[%d %s]", m.cfg.Name, res.Code, res.Description)
+	}
+	now := time.Now().Unix()
+	events := make([]*common.InvalidTokenEvent, 0, len(res.Data.List))
+	for _, regId := range res.Data.List {
+		events = append(events, &common.InvalidTokenEvent{
+			DeviceVendor: m.cfg.Name,
+			PackageName:  m.cfg.Package,
+			Token:        regId,
+			Status:       common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN,
+			Reason:       "feedback invalid regId",
+			Timestamp:    now,
+		})
+	}
+	return events, nil
+}
+
+//撤回消息，小米只能取消尚未下发的定时消息
+func (m *Client) Recall(ctx context.Context, requests ...*common.RecallRequest) ([]*common.RecallResult, error) {
+	list := make([]*common.RecallResult, 0, len(requests))
+	for _, request := range requests {
+		item := &common.RecallResult{DeviceVendor: m.cfg.Name, PackageName: m.cfg.Package, RequestId: request.RequestId}
+		res, err := m.mipush.DeleteScheduleJob(ctx, request.RequestId)
+		if err != nil {
+			item.Description = err.Error()
+		} else {
+			item.Success = res.Code == 0
+			item.Description = res.Reason
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+//按消息id查询推送统计，taskIds为推送返回的消息id
+func (m *Client) GetTaskStats(taskIds ...string) ([]*common.TaskStats, error) {
+	list := make([]*common.TaskStats, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		res, err := m.mipush.GetMessageStatusByMsgID(context.Background(), taskId)
+		if err != nil {
+			return list, fmt.Errorf("%s get task stats error:[%v]", m.cfg.Name, err)
+		}
+		if res.Code != 0 {
+			return list, fmt.Errorf("%s get task stats error:[%d %s]", m.cfg.Name, res.Code, res.Description)
+		}
+		data := res.Data.Data
+		list = append(list, &common.TaskStats{
+			TaskId:       taskId,
+			DeviceVendor: m.cfg.Name,
+			PackageName:  m.cfg.Package,
+			Sent:         int64(data.Resolved),
+			Delivered:    int64(data.Delivered),
+			Clicked:      int64(data.Click),
+		})
+	}
+	return list, nil
+}
+
+//按天查询应用推送统计
+func (m *Client) GetDailyStats(start, end time.Time) ([]*common.DailyStats, error) {
+	res, err := m.mipush.Stats(context.Background(), start.Format(common.STATS_DATE_FORMAT), end.Format(common.STATS_DATE_FORMAT), m.cfg.Package)
+	if err != nil {
+		return nil, fmt.Errorf("%s get daily stats error:[%v]", m.cfg.Name, err)
+	}
+	if res.Code != 0 {
+		return nil, fmt.Errorf("%s get daily stats error:[%d %s]", m.cfg.Name, res.Code, res.Description)
+	}
+	list := make([]*common.DailyStats, 0, len(res.Data.Data))
+	for _, data := range res.Data.Data {
+		list = append(list, &common.DailyStats{
+			Date:         data.Date,
+			DeviceVendor: m.cfg.Name,
+			PackageName:  m.cfg.Package,
+			Sent:         data.SingleRecipients + data.AliasRecipients + data.UserAccountRecipients + data.RegIDRecipients + data.BroadcastRecipients,
+			Delivered:    data.Received,
+			Clicked:      data.Click,
+		})
+	}
+	return list, nil
+}
+
+func (m *Client) uploadImgFile(filePath string) (string, error) {
+	result, err := m.mipush.UploadImgFile(context.TODO(), filePath)
+	if err != nil {
+		return "", err
+	}
+	if result.Code != 0 {
+		return "", fmt.Errorf("upload img error:[%d %s]", result.Code, result.Description)
+	}
+	return result.Data.PicUrl, nil
+}
+
+func (m *Client) formatMsg(msg *common.Msg) (*Message, error) {
+	msg1 := NewAndroidMessage(msg.MsgTitle, msg.MsgBody).SetPayload(msg.MsgAction).SetNotifyID(msg.Id).SetTimeToSend(time.Now().Unix() * 1000)
+	paramsData, dropped, err := common.NewCallbackParam(msg, m.cfg.Package).Encode(m.cfg.CallbackSecret, m.cfg.CallbackExpire, MaxCallbackParamLength)
+	if err != nil {
+		log.WithError(err).WithField("msgId", msg.Id).Warnf("%s callback param too long, send without it", m.cfg.Name)
+	} else if len(dropped) > 0 {
+		log.WithField("msgId", msg.Id).WithField("dropped", dropped).Warnf("%s callback extra dropped by length limit", m.cfg.Name)
+	}
+	msg1 = msg1.SetCallback(common.CallbackUrl(m.cfg.Redirect, m.Name(), m.cfg.Package, m.cfg.CallbackToken), paramsData)
+	if msg.ImgUrl != "" {
+		picUrl, err := common.UploadImage(context.Background(), msg.ImgUrl, m.cfg.Name, m.cfg.Package, common.XiaomiBigPictureSpec, MediaCacheTTL, m.uploadImgFile)
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"imgUrl": msg.ImgUrl,
+			}).Errorf("%s upload img error", m.cfg.Name)
+		}
+		if picUrl != "" {
+			msg1.Extra["notification_bigPic_uri"] = picUrl
+			msg1.Extra["notification_style_type"] = "2"
+		}
+	}
+	msg1.Extra["channel_id"] = msg.ChannelID
+	return msg1, nil
+}
+
 type CallBackItem struct {
 	Param     string                 `json:"param"`
 	Status    int64                  `json:"type"`
@@ -107,22 +282,49 @@ type CallBackItem struct {
 	Extra     map[string]interface{} `json:"extra"`
 }
 
+//回执签名请求头，签名为 hex(hmac-sha256(appSecret, 请求体))
+const CALLBACK_SIGN_HEADER = "X-Xiaomi-Signature"
+
+//开启callback_sign时校验请求体签名，再校验回执地址中的token
+func (c *Client) VerifyCallback(r *http.Request) error {
+	if c.cfg.CallbackSign {
+		body, err := common.ReadCallbackBody(r)
+		if err != nil {
+			return err
+		}
+		if err := common.VerifyCallbackSign(c.cfg.Name, r.Header.Get(CALLBACK_SIGN_HEADER), c.callbackSign(body)); err != nil {
+			return err
+		}
+	}
+	return common.VerifyCallbackToken(r, c.cfg.Name, c.cfg.CallbackToken)
+}
+
+func (c *Client) callbackSign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(c.cfg.AppSecret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[string]interface{}, error) {
 	res := map[string]interface{}{"errno": 0, "errmsg": "success"}
+	if err := r.ParseForm(); err != nil {
+		log.WithError(err).WithField("deviceVendor", c.cfg.Name).Errorf("invalid callback form")
+		return nil, nil, err
+	}
 	form := r.Form
 	log.WithFields(log.Fields{
 		"deviceVendor": c.cfg.Name,
-		"url":          r.URL.RequestURI(),
-		"header":       r.Header,
-		"form":         form,
+		"url":          common.RedactUri(r),
+		"header":       common.RedactHeader(r),
+		"form":         common.RedactForm(form),
 	}).Tracef("callback")
 	data := ""
 	if data = form.Get("data"); data == "" {
 		log.WithFields(log.Fields{
 			"deviceVendor": c.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
-			"form":         form,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
+			"form":         common.RedactForm(form),
 		}).Errorf("empty params data")
 		return nil, nil, fmt.Errorf("empty params data")
 	}
@@ -131,17 +333,16 @@ func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[str
 	if err != nil {
 		log.WithFields(log.Fields{
 			"deviceVendor": c.cfg.Name,
-			"url":          r.URL.RequestURI(),
-			"header":       r.Header,
-			"form":         form,
+			"url":          common.RedactUri(r),
+			"header":       common.RedactHeader(r),
+			"form":         common.RedactForm(form),
 		}).Errorf("empty params data")
 		return nil, nil, err
 	}
 	var callbackResponse common.CallbackResponse
 	for requestId, val := range reciver {
 		item := &common.CallbackResponseItem{}
-		params := common.CallbackParam{}
-		err := json.Unmarshal([]byte(val.Param), &params)
+		params, err := common.DecodeCallbackParam(c.cfg.Name, val.Param, c.cfg.CallbackSecret)
 		if err != nil {
 			log.WithError(err).WithField("deviceVendor", c.cfg.Name).Errorf("invalid callback param: %v", val.Param)
 			continue
@@ -151,26 +352,42 @@ func (c *Client) PushReciver(r *http.Request) (*common.CallbackResponse, map[str
 		item.RequestId = requestId
 		item.Timestamp = val.TimeStamp
 		item.PackageName = params.Package
+		if item.PackageName == "" {
+			item.PackageName = c.cfg.Package
+		}
+		item.Extra = params.Extra
 		status := val.Status
+		eventType := common.EVENT_TYPE_FAILED
 		switch val.Status {
-		case 1:
+		case 1: //送达
 			status = common.CALLBACK_STATUS_OK
-		case 2:
+			eventType = common.EVENT_TYPE_DELIVERED
+		case 2: //点击
 			status = common.CALLBACK_STATUS_OK
-		case 3:
+			eventType = common.EVENT_TYPE_CLICKED
+		case 3: //送达与点击，分别生成送达和点击事件
 			status = common.CALLBACK_STATUS_OK
+			eventType = common.EVENT_TYPE_DELIVERED
 		case 16:
 			status = common.CALLBACK_STATUS_INVALID_DEVICE_TOKEN
 		case 32:
 			status = common.CALLBACK_STATUS_DISABLE_PUSH
 		case 64: //64：目标设备不符合过滤条件（包括网络条件不符合、地理位置不符合、App版本不符合、机型不符合、地区语言不符合等）。
+			status = common.CALLBACK_STATUS_FILTERED
+			eventType = common.EVENT_TYPE_FILTERED
 		case 128:
 			status = common.CALLBACK_STATUS_PUSH_TOTAL_LIMIT
 		default:
 			status = common.CALLBACK_STATUS_NEED_RETRY
 		}
 		item.Status = status
+		item.EventType = eventType
 		callbackResponse.Data = append(callbackResponse.Data, item)
+		if val.Status == 3 {
+			clicked := *item
+			clicked.EventType = common.EVENT_TYPE_CLICKED
+			callbackResponse.Data = append(callbackResponse.Data, &clicked)
+		}
 	}
 	return &callbackResponse, res, nil
 }
@@ -0,0 +1,44 @@
+package xiaomipush
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"push_sdks/common"
+	"push_sdks/config"
+)
+
+func TestVerifyCallback(t *testing.T) {
+	c := &Client{cfg: &config.PushServerCfg{Name: "xiaomi", AppSecret: "secret", CallbackSign: true}}
+	body := `data={"id":{"type":1,"targets":"a"}}`
+	r := httptest.NewRequest("POST", "/callback", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set(CALLBACK_SIGN_HEADER, c.callbackSign([]byte(body)))
+	if err := c.VerifyCallback(r); err != nil {
+		t.Errorf("valid callback sign rejected: %v", err)
+	}
+	if response, _, err := c.PushReciver(r); err != nil || len(response.Data) != 1 {
+		t.Errorf("callback body should still be readable after verify: %v %v", response, err)
+	}
+
+	r = httptest.NewRequest("POST", "/callback", strings.NewReader(body))
+	r.Header.Set(CALLBACK_SIGN_HEADER, c.callbackSign([]byte("data={}")))
+	if err := c.VerifyCallback(r); !errors.Is(err, common.ErrCallbackUnauthorized) {
+		t.Errorf("forged callback sign accepted: %v", err)
+	}
+}
+
+func TestPushReciverDeliveredAndClicked(t *testing.T) {
+	c := &Client{cfg: &config.PushServerCfg{Name: "xiaomi"}}
+	r := httptest.NewRequest("POST", "/callback", strings.NewReader(`data={"id":{"type":3,"targets":"a"}}`))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, _, err := c.PushReciver(r)
+	if err != nil || len(response.Data) != 2 {
+		t.Fatalf("unexpected callback response %v %v", response, err)
+	}
+	if response.Data[0].EventType != common.EVENT_TYPE_DELIVERED || response.Data[1].EventType != common.EVENT_TYPE_CLICKED {
+		t.Errorf("unexpected event types %s %s", response.Data[0].EventType, response.Data[1].EventType)
+	}
+}